@@ -0,0 +1,154 @@
+"""
+Thin ctypes bindings for libbws, the C shared library of bws.
+
+Build the library with "go build -buildmode=c-shared -o libbws.so ./cmd/libbws" and put it next to this file,
+or point $BWS_LIBRARY at it. Every function returns the decoded JSON of the library and raises a BWSError on failures.
+
+    import bws
+
+    bws.set_options({"mainDirs": ["/home/me/"], "secondaryDirs": []})
+    with bws.Searcher() as searcher:
+        for result in searcher.search("holiday kind:video", limit=10)["results"]:
+            print(result["path"])
+"""
+
+import ctypes
+import json
+import os
+import sys
+
+LIBRARY_ENV = "BWS_LIBRARY"  # the environment variable with the path of the library
+
+_library = None
+
+
+class BWSError(Exception):
+    """A call into the library failed."""
+
+
+class SearchCancelled(BWSError):
+    """The search was cancelled with Searcher.cancel."""
+
+
+def _library_path():
+    """Returns the path of the library, from $BWS_LIBRARY or next to this file."""
+    if os.environ.get(LIBRARY_ENV):
+        return os.environ[LIBRARY_ENV]
+
+    if sys.platform == "win32":
+        name = "libbws.dll"
+    elif sys.platform == "darwin":
+        name = "libbws.dylib"
+    else:
+        name = "libbws.so"
+
+    return os.path.join(os.path.dirname(os.path.abspath(__file__)), name)
+
+
+def _load():
+    """Loads the library on the first call and declares the signatures of its functions."""
+    global _library
+    if _library is not None:
+        return _library
+
+    library = ctypes.CDLL(_library_path())
+
+    # the returned strings are kept as void pointers, so they can be freed after being read
+    library.bws_searcher_new.argtypes = []
+    library.bws_searcher_new.restype = ctypes.c_longlong
+    library.bws_searcher_free.argtypes = [ctypes.c_longlong]
+    library.bws_searcher_free.restype = None
+    library.bws_search.argtypes = [ctypes.c_longlong, ctypes.c_char_p]
+    library.bws_search.restype = ctypes.c_void_p
+    library.bws_cancel.argtypes = [ctypes.c_longlong]
+    library.bws_cancel.restype = None
+    library.bws_status.argtypes = []
+    library.bws_status.restype = ctypes.c_void_p
+    library.bws_set_options.argtypes = [ctypes.c_char_p]
+    library.bws_set_options.restype = ctypes.c_void_p
+    library.bws_free.argtypes = [ctypes.c_void_p]
+    library.bws_free.restype = None
+
+    _library = library
+    return _library
+
+
+def _decode(pointer):
+    """Reads and frees a JSON string returned by the library and raises its error, if it holds one."""
+    library = _load()
+    try:
+        value = json.loads(ctypes.string_at(pointer).decode("utf-8"))
+    finally:
+        library.bws_free(pointer)
+
+    if isinstance(value, dict) and "error" in value:
+        if value.get("cancelled"):
+            raise SearchCancelled(value["error"])
+        raise BWSError(value["error"])
+
+    return value
+
+
+def set_options(config):
+    """Applies the config, a dict with the keys of a bws config file. Only the cache of this process is affected, not the daemon."""
+    _decode(_load().bws_set_options(json.dumps(config).encode("utf-8")))
+
+
+def status():
+    """Returns the status of the cache generation as a dict, "elapsed" and "eta" are in nanoseconds."""
+    return _decode(_load().bws_status())
+
+
+class Searcher:
+    """
+    Runs searches, that can be cancelled from another thread with cancel.
+
+    Close it (or use it as a context manager) once it isn't needed anymore.
+    """
+
+    def __init__(self):
+        self._handle = _load().bws_searcher_new()
+
+    def search(self, query, ext=None, extended=False, scopes=None, limit=0, wait=True):
+        """
+        Returns the response of the search as a dict with the ranked "results" and whether they're "incomplete".
+
+        With wait (the default) a search on a cache, that is still being generated, waits for it, so the results are never partial.
+        Raises SearchCancelled, if cancel got called while it ran.
+        """
+        self._check()
+        params = {
+            "query": query,
+            "ext": list(ext or []),
+            "extended": extended,
+            "scopes": list(scopes or []),
+            "limit": limit,
+            "wait": wait,
+        }
+
+        return _decode(_load().bws_search(self._handle, json.dumps(params).encode("utf-8")))
+
+    def cancel(self):
+        """Cancels the searches of this searcher, that are running right now."""
+        self._check()
+        _load().bws_cancel(self._handle)
+
+    def close(self):
+        """Cancels the running searches and frees the searcher, closing it twice does nothing."""
+        if self._handle:
+            _load().bws_searcher_free(self._handle)
+            self._handle = 0
+
+    def _check(self):
+        if not self._handle:
+            raise BWSError("the searcher is closed")
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc_info):
+        self.close()
+
+    def __del__(self):
+        if getattr(self, "_handle", 0) and _library is not None:
+            self.close()
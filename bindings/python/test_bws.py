@@ -0,0 +1,178 @@
+"""
+Tests for the ctypes bindings, run them with: python3 -m unittest discover -s bindings/python
+
+The library gets built into a temporary folder, unless $BWS_LIBRARY points at one. The tests only run on Linux.
+"""
+
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+import threading
+import unittest
+
+import bws
+
+REPO_ROOT = os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+_temp_dir = None
+_main_dir = None
+_extra_dir = None
+
+
+def _write(path, content=""):
+    os.makedirs(os.path.dirname(path), exist_ok=True)
+    with open(path, "w") as file:
+        file.write(content)
+
+
+def setUpModule():
+    global _temp_dir, _main_dir, _extra_dir
+
+    if not sys.platform.startswith("linux"):
+        raise unittest.SkipTest("the tests only run on Linux")
+
+    _temp_dir = tempfile.mkdtemp(prefix="bws-test-")
+
+    # never use a daemon, that might be running, so the tests only see their own files
+    os.environ["BWS_SOCKET"] = os.path.join(_temp_dir, "none.sock")
+
+    if not os.environ.get(bws.LIBRARY_ENV):
+        if shutil.which("go") is None:
+            raise unittest.SkipTest("go isn't installed and $%s isn't set" % bws.LIBRARY_ENV)
+
+        library = os.path.join(_temp_dir, "libbws.so")
+        subprocess.run(["go", "build", "-buildmode=c-shared", "-o", library, "./cmd/libbws"], cwd=REPO_ROOT, check=True)
+        os.environ[bws.LIBRARY_ENV] = library
+
+    _main_dir = os.path.join(_temp_dir, "main")
+    _extra_dir = os.path.join(_temp_dir, "extra")
+
+    _write(os.path.join(_main_dir, "report.pdf"), "%PDF-1.4")
+    _write(os.path.join(_main_dir, "reports", "report-2023.txt"), "numbers")
+    _write(os.path.join(_main_dir, "notes", "holiday.txt"), "beach")
+    os.makedirs(os.path.join(_main_dir, "Holiday Photos"))
+    _write(os.path.join(_extra_dir, "holiday-extra.txt"), "mountains")
+
+    # enough files, that a search takes a moment, so there is something to cancel
+    for index in range(2000):
+        _write(os.path.join(_main_dir, "bulk", "folder%d" % (index % 50), "file%d.txt" % index))
+
+    bws.set_options({
+        "mainDirs": [_main_dir],
+        "excludeSubMainDirs": [],
+        "secondaryDirs": [],
+        "excludeDirs": [],
+        "scopes": [{"name": "extra", "roots": [_extra_dir], "extended": True}],
+    })
+
+
+def tearDownModule():
+    if _temp_dir is not None:
+        shutil.rmtree(_temp_dir, ignore_errors=True)
+
+
+def paths(response):
+    return [result["path"] for result in response["results"]]
+
+
+class SearchTest(unittest.TestCase):
+    def setUp(self):
+        self.searcher = bws.Searcher()
+
+    def tearDown(self):
+        self.searcher.close()
+
+    def test_finds_files_and_folders(self):
+        response = self.searcher.search("holiday")
+
+        self.assertFalse(response["incomplete"])
+        found = paths(response)
+        self.assertTrue(any(path.endswith("holiday.txt") for path in found), found)
+        self.assertTrue(any("Holiday Photos" in path for path in found), found)
+
+    def test_results_have_their_fields(self):
+        result = self.searcher.search("report.pdf")["results"][0]
+
+        self.assertTrue(result["path"].endswith("report.pdf"))
+        self.assertEqual(result["scope"], "main")
+        self.assertGreater(result["points"], 0)
+        self.assertIn("mime", result)
+
+    def test_extensions(self):
+        found = paths(self.searcher.search("report", ext=[".pdf"]))
+
+        self.assertEqual(len(found), 1)
+        self.assertTrue(found[0].endswith("report.pdf"))
+
+    def test_limit(self):
+        self.assertEqual(len(self.searcher.search("file", limit=5)["results"]), 5)
+
+    def test_extended_and_scopes(self):
+        self.assertFalse(any("holiday-extra" in path for path in paths(self.searcher.search("holiday"))))
+        self.assertTrue(any("holiday-extra" in path for path in paths(self.searcher.search("holiday", extended=True))))
+
+        found = paths(self.searcher.search("holiday", scopes=["extra"]))
+        self.assertEqual(len(found), 1)
+        self.assertTrue(found[0].endswith("holiday-extra.txt"))
+
+    def test_negative_limit(self):
+        with self.assertRaises(bws.BWSError):
+            self.searcher.search("report", limit=-1)
+
+    def test_closed_searcher(self):
+        self.searcher.close()
+        self.searcher.close()
+
+        with self.assertRaises(bws.BWSError):
+            self.searcher.search("report")
+
+    def test_cancel(self):
+        # whether a search gets cancelled in time depends on the timing, but every search has to end and later ones have to work
+        for _ in range(20):
+            outcome = []
+
+            def run():
+                try:
+                    outcome.append(self.searcher.search("file"))
+                except bws.SearchCancelled as err:
+                    outcome.append(err)
+
+            thread = threading.Thread(target=run)
+            thread.start()
+            self.searcher.cancel()
+            thread.join(timeout=30)
+
+            self.assertFalse(thread.is_alive())
+            self.assertEqual(len(outcome), 1)
+
+        self.assertEqual(len(self.searcher.search("file")["results"]), 2000)
+
+    def test_context_manager(self):
+        with bws.Searcher() as searcher:
+            self.assertTrue(paths(searcher.search("report")))
+
+        with self.assertRaises(bws.BWSError):
+            searcher.search("report")
+
+
+class OptionsTest(unittest.TestCase):
+    def test_unknown_key(self):
+        with self.assertRaises(bws.BWSError):
+            bws.set_options({"noSuchKey": True})
+
+    def test_invalid_value(self):
+        with self.assertRaises(bws.BWSError):
+            bws.set_options({"mainDirs": [os.path.join(_temp_dir, "missing")]})
+
+    def test_status(self):
+        bws.Searcher().search("report")
+        current = bws.status()
+
+        for key in ("phase", "scope", "dirsVisited", "entriesIndexed", "elapsed", "eta"):
+            self.assertIn(key, current)
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -4,6 +4,7 @@ package bws
 // <---------------------------------------------------------------------------------------------------->
 
 import (
+	"fmt"
 	"log"
 	"runtime"
 	"time"
@@ -26,35 +27,22 @@ func init() {
 	go updateCache()
 }
 
-// updateCache updates with the use of tickers both the MainDirs and the SecondaryDirs
+// updateCache checks in a fixed interval, which scopes are due for an update and updates them
 func updateCache() {
-	// create tickers for how often the FileSystem components are supossed to update
-	mainDirsTicker := time.NewTicker(3 * time.Minute)
-	defer mainDirsTicker.Stop()
-
-	secondaryDirsTicker := time.NewTicker(30 * time.Minute)
-	defer secondaryDirsTicker.Stop()
-
-	for {
-		select {
-		case <-mainDirsTicker.C:
-			if !cache.EntrieFilesystem.SetupProperly {
-				continue
-			}
-
-			// check if the Filesystem is Updateable, if so update it, otherwise wait for the next cycle
-			if cache.EntrieFilesystem.Updateable {
-				cache.EntrieFilesystem.Update(config.BWSConfig.MainDirs, true)
-				runtime.GC()
-			}
-		case <-secondaryDirsTicker.C:
-			if !cache.EntrieFilesystem.SetupProperly {
-				continue
-			}
+	// the interval is the finest granularity a scope can be refreshed in
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		fs := cache.EntrieFilesystem()
+		if !fs.SetupProperly.Load() {
+			continue
+		}
 
-			// check if the Filesystem is Updateable, if so update it, otherwise wait for the next cycle
-			if cache.EntrieFilesystem.Updateable {
-				cache.EntrieFilesystem.Update(config.BWSConfig.SecondaryDirs, false)
+		for _, scope := range config.BWSConfig.Scopes {
+			// check if the Filesystem is Updateable, if so update the scope, otherwise wait for the next cycle
+			if fs.Updateable.Load() && fs.Outdated(scope) {
+				fs.Update(scope)
 				runtime.GC()
 			}
 		}
@@ -62,52 +50,68 @@ func updateCache() {
 }
 
 /*
-baseSearch is a wrapper around the search and rank functions and returns the ranked search results at the end.
+baseSearch is a wrapper around the search and rank functions and returns the ranked search results from the provided scopes at the end.
 
+If the cache hasn't been generated yet, its generation gets started in the background and we search through what has been found so far.
 Additionally if baseSearch was launched by GoSearchWithBreak it can be stopped at any point with the forceStopChan.
 If the search was stopped early we return a true, otherwise false.
 */
-func baseSearch(searchString string, fileExtensions []string, extendedSearch bool, forceStopChan chan bool) ([]string, bool) {
-	// check if the FileSystem is setup properly, if not reset it by regenerating it
-	if !cache.EntrieFilesystem.SetupProperly {
-		ForceUpdateCache()
+func baseSearch(searchString string, fileExtensions []string, scopes []string, forceStopChan chan bool) (*Response, bool) {
+	// check if the FileSystem is setup properly, if not regenerate it in the background
+	fs := cache.EntrieFilesystem()
+	if !fs.SetupProperly.Load() {
+		fs = cache.Generate(config.BWSConfig.Scopes)
 	}
 
 	// make it so while we search we can't update the FileSystem
-	cache.EntrieFilesystem.Updateable = false
-	defer func() {
-		cache.EntrieFilesystem.Updateable = true
-	}()
+	fs.Updateable.Store(false)
+	defer fs.Updateable.Store(true)
+
+	// check if the scopes we search have been crawled completely before we search them, so we never mark a complete result as incomplete
+	incomplete := false
+	fs.RLock()
+	for _, scope := range scopes {
+		// names that don't belong to a scope of the config will never be crawled
+		if config.BWSConfig.Scope(scope) == nil {
+			continue
+		}
+
+		if scopeCache, ok := fs.Scopes[scope]; !ok || !scopeCache.Ready {
+			incomplete = true
+		}
+	}
+	fs.RUnlock()
 
 	// get the filepaths and names
-	results, pattern := search.Start(search.NewSearchString(searchString, fileExtensions), extendedSearch, forceStopChan)
+	results, pattern := search.Start(fs, search.NewSearchString(searchString, fileExtensions), scopes, forceStopChan)
 
 	// check if we have to stop the baseSearch
 	if len(forceStopChan) > 0 {
-		return []string{}, true
+		return newResponse(&[]search.RankedFile{}, false, fs), true
 	}
 
-	output := *search.Rank(results, pattern, forceStopChan)
+	// rank and sort the files
+	rankedFiles := search.Rank(results, pattern, forceStopChan)
 
 	// check if we have to stop the baseSearch
 	if len(forceStopChan) > 0 {
-		return []string{}, true
+		return newResponse(&[]search.RankedFile{}, false, fs), true
 	}
 
-	// rank and sort the files
-	return output, false
+	return newResponse(rankedFiles, incomplete, fs), false
 }
 
 /*
 Search takes in any substring that you want to search for through all filenames. You may add any amount of file extensions as well.
-The extendedSearch flag dictates, if we search through the SecondaryDirs.
+The extendedSearch flag dictates, if we also search through the extended scopes, like the SecondaryDirs.
 To change the folders included/excluded in the search use the pkg/options set functions.
 
-On it's first execution the function will take longer, as it needs to generate the cache first.
+On it's first execution the function starts generating the cache in the background. Until that's done, the results only contain
+what has been found so far. Use DetailedSearch, if you need to know whether the results are complete.
 */
 func Search(searchString string, fileExtensions []string, extendedSearch bool) []string {
-	results, _ := baseSearch(searchString, fileExtensions, extendedSearch, make(chan bool, 1)) // insert a dummy channel, as it's not needed here
-	return results
+	response, _ := baseSearch(searchString, fileExtensions, config.BWSConfig.SearchScopes(extendedSearch), make(chan bool, 1)) // insert a dummy channel, as it's not needed here
+	return response.Paths()
 }
 
 /*
@@ -117,6 +121,39 @@ This function should be started as a goroutine and it can be cancelled early by
 If it breaks early, it returns a true, otherwise false.
 */
 func GoSearchWithBreak(searchString string, fileExtensions []string, extendedSearch bool, breakChan chan bool) ([]string, bool) {
+	response, brokenEarly := GoDetailedSearchWithBreak(searchString, fileExtensions, extendedSearch, breakChan)
+	return response.Paths(), brokenEarly
+}
+
+/*
+DetailedSearch behaves exactly like Search, but returns a Response instead of just the paths.
+
+If the cache is still being generated, the Response is marked as Incomplete. Once its Ready channel gets closed, the cache is done
+and you can run the same search again for the complete results.
+*/
+func DetailedSearch(searchString string, fileExtensions []string, extendedSearch bool) *Response {
+	response, _ := baseSearch(searchString, fileExtensions, config.BWSConfig.SearchScopes(extendedSearch), make(chan bool, 1)) // insert a dummy channel, as it's not needed here
+	return response
+}
+
+// GoDetailedSearchWithBreak behaves exactly like GoSearchWithBreak, but returns a Response instead of just the paths.
+func GoDetailedSearchWithBreak(searchString string, fileExtensions []string, extendedSearch bool, breakChan chan bool) (*Response, bool) {
+	return GoSearchScopesWithBreak(searchString, fileExtensions, config.BWSConfig.SearchScopes(extendedSearch), breakChan)
+}
+
+/*
+SearchScopes behaves exactly like DetailedSearch, but instead of the extendedSearch flag you provide the names of the scopes to search through.
+Names that don't belong to a scope of the config are ignored.
+
+To add or change scopes use the pkg/options scope functions.
+*/
+func SearchScopes(searchString string, fileExtensions []string, scopes []string) *Response {
+	response, _ := baseSearch(searchString, fileExtensions, scopes, make(chan bool, 1)) // insert a dummy channel, as it's not needed here
+	return response
+}
+
+// GoSearchScopesWithBreak behaves exactly like GoDetailedSearchWithBreak, but instead of the extendedSearch flag you provide the names of the scopes to search through.
+func GoSearchScopesWithBreak(searchString string, fileExtensions []string, scopes []string, breakChan chan bool) (*Response, bool) {
 	forceStopChan := make(chan bool, 1)
 
 	go func() {
@@ -127,15 +164,85 @@ func GoSearchWithBreak(searchString string, fileExtensions []string, extendedSea
 		}
 	}()
 
-	return baseSearch(searchString, fileExtensions, extendedSearch, forceStopChan)
+	return baseSearch(searchString, fileExtensions, scopes, forceStopChan)
 }
 
 /*
-ForceUpdateCache updates the cache regardless of it's state.
+ForceUpdateCache updates the cache regardless of it's state and waits until it's done.
 
 This function is generally not needed. Though it can be useful, if you want to generate the cache early, before your first search.
 */
 func ForceUpdateCache() {
-	cache.EntrieFilesystem = cache.New(config.BWSConfig.MainDirs, config.BWSConfig.SecondaryDirs)
+	<-cache.Generate(config.BWSConfig.Scopes).Ready()
+	runtime.GC()
+}
+
+/*
+UpdateScopes updates the provided scopes right away and waits until it's done. If no scopes are provided, all of them get updated.
+
+If the cache hasn't been generated yet, it gets generated completely instead. Names that don't belong to a scope of the config are an error.
+*/
+func UpdateScopes(scopes []string) error {
+	toUpdate := []*config.Scope{}
+	for _, name := range scopes {
+		scope := config.BWSConfig.Scope(name)
+		if scope == nil {
+			return fmt.Errorf("there is no scope called '%s'", name)
+		}
+
+		toUpdate = append(toUpdate, scope)
+	}
+
+	if len(toUpdate) < 1 {
+		toUpdate = config.BWSConfig.Scopes
+	}
+
+	// the cache has to be complete, before single scopes can be updated
+	fs := cache.EntrieFilesystem()
+	if !fs.SetupProperly.Load() {
+		ForceUpdateCache()
+		return nil
+	}
+
+	for _, scope := range toUpdate {
+		fs.Update(scope)
+	}
 	runtime.GC()
+
+	return nil
+}
+
+/*
+IndexReady returns a channel that gets closed, once the cache has been fully generated.
+
+If the cache is currently not being generated, the channel belongs to the cache that is already in use.
+*/
+func IndexReady() <-chan struct{} {
+	return cache.EntrieFilesystem().Ready()
+}
+
+/*
+BrokenLinks returns the paths of all links inside of the provided scopes, whose target doesn't exist (anymore).
+If no scopes are provided, the broken links of all scopes are returned.
+
+The links are collected during the generation of the cache, so they're only as recent as the last update of their scope.
+*/
+func BrokenLinks(scopes []string) []string {
+	fs := cache.EntrieFilesystem()
+	brokenLinks := []string{}
+
+	if len(scopes) < 1 {
+		scopes = config.BWSConfig.SearchScopes(true)
+	}
+
+	fs.RLock()
+	defer fs.RUnlock()
+
+	for _, scope := range scopes {
+		if scopeCache, ok := fs.Scopes[scope]; ok {
+			brokenLinks = append(brokenLinks, scopeCache.BrokenLinks...)
+		}
+	}
+
+	return brokenLinks
 }
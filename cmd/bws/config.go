@@ -0,0 +1,109 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/skillptm/bws/pkg/options"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// runConfig runs one of the config actions: show or validate
+func runConfig(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "usage: bws config <show|validate> [flags]")
+		return exitError
+	}
+
+	switch args[0] {
+	case "show":
+		return runConfigShow(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprintln(os.Stderr, "usage: bws config <show|validate> [flags]")
+		return exitOK
+	}
+
+	return fail(fmt.Errorf("unknown config action '%s', use show or validate", args[0]))
+}
+
+// runConfigShow prints the config, that results from the defaults and the config file, as JSON
+func runConfigShow(args []string) int {
+	flags := newFlagSet("config show", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+	outputValues := addOutputFlags(flags)
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	// the config is JSON either way, so the plain format is the indented JSON as well
+	err = writeValue(out, options.CurrentFile(), func(file *options.File) string {
+		data, _ := json.MarshalIndent(file, "", "  ")
+		return string(data)
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+/*
+runConfigValidate checks the config file from the positional argument, --config, the environment or the default path.
+
+It prints every invalid key and exits with the exitError, if there is any.
+*/
+func runConfigValidate(args []string) int {
+	flags := newFlagSet("config validate", "[flags] [file]")
+	configFile := flags.String("config", "", "the path of the config file")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	if len(positional) > 1 {
+		return fail(errors.New("only one config file can be validated at once"))
+	}
+
+	filePath := *configFile
+	if len(positional) > 0 {
+		filePath = positional[0]
+	}
+
+	filePath = configPath(filePath)
+	if len(filePath) < 1 {
+		return fail(fmt.Errorf("there is no config file at %s and neither --config nor $%s are set", defaultConfigPath(), configEnv))
+	}
+
+	file, err := options.ReadFile(filePath)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := file.Apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s is invalid:\n%s\n", filePath, err.Error())
+		return exitError
+	}
+
+	fmt.Printf("%s is valid\n", filePath)
+
+	return exitOK
+}
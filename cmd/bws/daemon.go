@@ -0,0 +1,54 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/skillptm/bws/internal/daemon"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+runDaemon generates the cache and serves it to the other commands and the client package over a unix domain socket,
+until it gets interrupted or terminated.
+*/
+func runDaemon(args []string) int {
+	flags := newFlagSet("daemon", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+	socket := flags.String("socket", daemon.SocketPath(), fmt.Sprintf("the path of the socket to listen on (also set by $%s)", daemon.SocketEnv))
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	listener, err := daemon.Listen(*socket)
+	if err != nil {
+		return fail(err)
+	}
+
+	// closing the listener ends Serve and removes the socket file
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "bws: listening on %s\n", *socket)
+
+	if err := daemon.Serve(listener); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
@@ -0,0 +1,159 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skillptm/ssl/pkg/sslslices"
+
+	"github.com/skillptm/bws"
+	"github.com/skillptm/bws/pkg/client"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// runIndex runs one of the index actions: build, refresh or status
+func runIndex(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "usage: bws index <build|refresh|status> [flags]")
+		return exitError
+	}
+
+	switch args[0] {
+	case "build":
+		return runIndexBuild(args[1:])
+	case "refresh":
+		return runIndexRefresh(args[1:])
+	case "status":
+		return runIndexStatus(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprintln(os.Stderr, "usage: bws index <build|refresh|status> [flags]")
+		return exitOK
+	}
+
+	return fail(fmt.Errorf("unknown index action '%s', use build, refresh or status", args[0]))
+}
+
+// runIndexBuild generates the whole cache from scratch and prints the stats of its scopes afterwards
+func runIndexBuild(args []string) int {
+	flags := newFlagSet("index build", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+	progress := flags.Bool("progress", false, "print the progress of the crawl to stderr")
+	outputValues := addOutputFlags(flags)
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	if *progress {
+		defer bws.OnIndexProgress(printProgress)()
+	}
+
+	client.ForceUpdateCache()
+
+	return printStats(out, client.Stats())
+}
+
+// runIndexRefresh updates the scopes provided as positional arguments, or all of them, and prints their stats afterwards
+func runIndexRefresh(args []string) int {
+	flags := newFlagSet("index refresh", "[flags] [scope]...")
+	configFile := flags.String("config", "", "the path of the config file")
+	progress := flags.Bool("progress", false, "print the progress of the crawl to stderr")
+	outputValues := addOutputFlags(flags)
+
+	scopes, err := parseFlags(flags, args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	if *progress {
+		defer bws.OnIndexProgress(printProgress)()
+	}
+
+	if err := client.UpdateScopes(scopes); err != nil {
+		return fail(err)
+	}
+
+	stats := []bws.ScopeStats{}
+	for _, scopeStats := range client.Stats() {
+		if len(scopes) < 1 || sslslices.Contains(scopes, scopeStats.Scope) {
+			stats = append(stats, scopeStats)
+		}
+	}
+
+	return printStats(out, stats)
+}
+
+// runIndexStatus prints the IndexStatus
+func runIndexStatus(args []string) int {
+	flags := newFlagSet("index status", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+	outputValues := addOutputFlags(flags)
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	if err := writeValue(out, client.GetIndexStatus(), formatStatus); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// printProgress prints a single line with the progress of the crawl to stderr
+func printProgress(status bws.IndexStatus) {
+	phase := status.Phase
+	if len(status.Scope) > 0 {
+		phase += " " + status.Scope
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %d dirs, %d entries, %s\n", phase, status.DirsVisited, status.EntriesIndexed, status.Elapsed.Round(time.Millisecond))
+}
+
+// formatStatus returns the IndexStatus as "key: value" lines
+func formatStatus(status bws.IndexStatus) string {
+	lines := []string{
+		"phase:   " + status.Phase,
+		"scope:   " + status.Scope,
+		"root:    " + status.CurrentRoot,
+		fmt.Sprintf("dirs:    %d", status.DirsVisited),
+		fmt.Sprintf("entries: %d", status.EntriesIndexed),
+		fmt.Sprintf("elapsed: %s", status.Elapsed.Round(time.Millisecond)),
+		fmt.Sprintf("eta:     %s", status.ETA.Round(time.Second)),
+	}
+
+	return strings.Join(lines, "\n")
+}
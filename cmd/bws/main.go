@@ -0,0 +1,237 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skillptm/bws"
+	"github.com/skillptm/bws/pkg/client"
+	"github.com/skillptm/bws/pkg/options"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	exitOK        int = 0   // the command succeeded and found something
+	exitNoResults int = 1   // the command succeeded, but found nothing (like grep)
+	exitError     int = 2   // the command failed or was used wrong
+	exitAborted   int = 130 // the user aborted "bws pick" (like fzf)
+
+	configEnv string = "BWS_CONFIG" // the environment variable with the path of the config file
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// command is a sub command of the tool, run gets the arguments after the name of the command and returns the exit code
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands are all sub commands in the order they're listed in the usage
+var commands = []command{
+	{name: "search", summary: "search the cache for files and folders", run: runSearch},
+	{name: "index", summary: "build or refresh the cache, or show its status", run: runIndex},
+	{name: "config", summary: "show the current config or validate a config file", run: runConfig},
+	{name: "stats", summary: "show what the cache holds for every scope", run: runStats},
+	{name: "daemon", summary: "keep the cache in memory and serve it to the other commands", run: runDaemon},
+	{name: "serve", summary: "serve the search as an HTTP/JSON API", run: runServe},
+	{name: "pick", summary: "interactively search and print the picked results", run: runPick},
+	{name: "shell", summary: "print the ctrl-t and alt-c bindings of pick for bash, zsh or fish", run: runShell},
+	{name: "rpc", summary: "answer JSON-RPC 2.0 on stdin and stdout, for editor plugins", run: runRPC},
+}
+
+// stringList is a flag, that can be repeated and also takes comma separated values (e.g. "--ext .go,.md --ext txt")
+type stringList []string
+
+// <---------------------------------------------------------------------------------------------------->
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run picks the command from the args and returns its exit code
+func run(args []string) int {
+	if len(args) < 1 {
+		usage()
+		return exitError
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "bws: unknown command '%s'\n\n", args[0])
+	usage()
+
+	return exitError
+}
+
+// usage prints the sub commands and the exit codes to stderr
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bws <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nrun 'bws <command> -h' for the flags of a command")
+	fmt.Fprintf(os.Stderr, "the config file is read from --config, $%s or %s\n", configEnv, defaultConfigPath())
+	fmt.Fprintln(os.Stderr, "while 'bws daemon' runs, the other commands use its cache and its config instead of their own")
+	fmt.Fprintf(os.Stderr, "\nexit codes: %d success, %d no results, %d error, %d aborted pick\n", exitOK, exitNoResults, exitError, exitAborted)
+}
+
+// newFlagSet returns a FlagSet for the command, that prints its usage with the synopsis and reports errors instead of exiting
+func newFlagSet(name string, synopsis string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bws %s %s\n\nflags:\n", name, synopsis)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+/*
+parseFlags parses the flags from anywhere inside of the args and returns the remaining positional arguments,
+so "bws search report --ext .pdf" works just like "bws search --ext .pdf report". Everything after "--" is positional.
+*/
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return positional, err
+		}
+
+		remaining := flags.Args()
+		consumed := len(args) - len(remaining)
+
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, remaining...), nil
+		}
+
+		if len(remaining) < 1 {
+			return positional, nil
+		}
+
+		positional = append(positional, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// exitCode returns the exit code for an error of parseFlags, asking for help isn't an error
+func exitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	return exitError
+}
+
+// String returns the values joined by commas
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+// Set adds the comma separated values, leaving out empty ones
+func (list *stringList) Set(value string) error {
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); len(element) > 0 {
+			*list = append(*list, element)
+		}
+	}
+
+	return nil
+}
+
+// defaultConfigPath returns the path the config file is read from, if neither --config nor the configEnv is set
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "bws", "config.json")
+}
+
+/*
+configPath returns the path of the config file, that should be used.
+
+An explicit path from --config wins over the configEnv, which wins over the defaultConfigPath.
+Only the defaultConfigPath may not exist, in which case an empty string is returned and the default config is used.
+*/
+func configPath(explicit string) string {
+	if len(explicit) > 0 {
+		return explicit
+	}
+
+	if fromEnv := os.Getenv(configEnv); len(fromEnv) > 0 {
+		return fromEnv
+	}
+
+	if defaultPath := defaultConfigPath(); len(defaultPath) > 0 {
+		if _, err := os.Stat(defaultPath); err == nil {
+			return defaultPath
+		}
+	}
+
+	return ""
+}
+
+// loadConfig reads the config file from the configPath and applies it, if there is one
+func loadConfig(explicit string) error {
+	filePath := configPath(explicit)
+	if len(filePath) < 1 {
+		return nil
+	}
+
+	file, err := options.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Apply(); err != nil {
+		return fmt.Errorf("invalid config file %s:\n%s", filePath, err.Error())
+	}
+
+	return nil
+}
+
+// ensureIndex generates the cache, if that hasn't happened yet, and waits until it's complete. The cache of a daemon is left as it is.
+func ensureIndex() {
+	if client.Running() {
+		return
+	}
+
+	if bws.GetIndexStatus().Phase == bws.PhaseIdle {
+		bws.ForceUpdateCache()
+		return
+	}
+
+	<-bws.IndexReady()
+}
+
+// fail prints the err to stderr and returns the exitError
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "bws: %s\n", err.Error())
+	return exitError
+}
@@ -0,0 +1,143 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	formatPlain  string = "plain"  // one line per item, made to be read by humans or simple scripts
+	formatNUL    string = "nul"    // the plain items separated by NUL bytes instead of newlines, for "xargs -0"
+	formatJSON   string = "json"   // a single indented JSON value
+	formatNDJSON string = "ndjson" // one compact JSON value per line
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// output writes the items of a command to stdout in its format
+type output struct {
+	format string
+	writer *bufio.Writer
+}
+
+// outputFlags holds the values of the flags added by addOutputFlags
+type outputFlags struct {
+	format string
+	nul    bool
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// addOutputFlags adds the --format flag and its -0 shortcut to the flags
+func addOutputFlags(flags *flag.FlagSet) *outputFlags {
+	values := outputFlags{}
+
+	flags.StringVar(&values.format, "format", formatPlain, "the output format: plain, nul, json or ndjson")
+	flags.BoolVar(&values.nul, "0", false, "shortcut for --format nul")
+
+	return &values
+}
+
+// newOutput returns a pointer to an output for the values of the outputFlags, or an error if the format is unknown
+func newOutput(values *outputFlags) (*output, error) {
+	format := values.format
+	if values.nul {
+		format = formatNUL
+	}
+
+	switch format {
+	case formatPlain, formatNUL, formatJSON, formatNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown format '%s', use plain, nul, json or ndjson", format)
+	}
+
+	return &output{format: format, writer: bufio.NewWriter(os.Stdout)}, nil
+}
+
+/*
+writeList writes the items in the format of the out.
+
+The plain function turns an item into its line for the plain and nul format. JSON writes all items as one array, NDJSON one item per line.
+*/
+func writeList[T any](out *output, items []T, plain func(item T) string) error {
+	defer out.writer.Flush()
+
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(items)
+	case formatNDJSON:
+		encoder := json.NewEncoder(out.writer)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, item := range items {
+			out.record(plain(item))
+		}
+	}
+
+	return nil
+}
+
+// writeValue writes a single value in the format of the out, the plain function returns its text for the plain and nul format
+func writeValue[T any](out *output, value T, plain func(value T) string) error {
+	defer out.writer.Flush()
+
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(value)
+	case formatNDJSON:
+		return json.NewEncoder(out.writer).Encode(value)
+	default:
+		out.record(plain(value))
+	}
+
+	return nil
+}
+
+// writeJSON writes the value as indented JSON
+func (out *output) writeJSON(value any) error {
+	encoder := json.NewEncoder(out.writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+// record writes the text followed by a newline, or by a NUL byte in the nul format
+func (out *output) record(text string) {
+	out.writer.WriteString(text)
+
+	if out.format == formatNUL {
+		out.writer.WriteByte(0)
+	} else {
+		out.writer.WriteByte('\n')
+	}
+}
+
+// formatSize returns the size in bytes in a human readable form, using powers of 1024 (e.g. "1.5 GiB")
+func formatSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -0,0 +1,742 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/skillptm/bws"
+	bwssearch "github.com/skillptm/bws/internal/search"
+	"github.com/skillptm/bws/pkg/client"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	escAltScreen  string = "\x1b[?1049h"
+	escMainScreen string = "\x1b[?1049l"
+	escHideCursor string = "\x1b[?25l"
+	escShowCursor string = "\x1b[?25h"
+	escHome       string = "\x1b[H"
+	escClearLine  string = "\x1b[K"
+
+	styleReset      string = "\x1b[0m"
+	styleBold       string = "\x1b[1m"
+	styleDim        string = "\x1b[2m"
+	styleMatchStart string = "\x1b[33m" // the part of a path, that matched the query, is yellow
+	styleMatchEnd   string = "\x1b[39m"
+
+	actionNone   int = 0
+	actionAccept int = 1
+	actionAbort  int = 2
+
+	pickerChrome int = 3 // the rows, that aren't part of the list: the prompt, the status and the info of the current result
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// controlKeys are the actions of the control characters, like ctrl-u
+var controlKeys = map[byte]string{
+	0x01: "home",       // ctrl-a
+	0x02: "left",       // ctrl-b
+	0x03: "abort",      // ctrl-c
+	0x04: "delete",     // ctrl-d
+	0x05: "end",        // ctrl-e
+	0x06: "right",      // ctrl-f
+	0x07: "abort",      // ctrl-g
+	0x08: "backspace",  // ctrl-h
+	0x09: "tab",        // tab
+	0x0a: "down",       // ctrl-j
+	0x0b: "up",         // ctrl-k
+	0x0d: "enter",      // enter
+	0x0e: "down",       // ctrl-n
+	0x10: "up",         // ctrl-p
+	0x11: "abort",      // ctrl-q
+	0x15: "clearLine",  // ctrl-u
+	0x17: "deleteWord", // ctrl-w
+	0x7f: "backspace",  // backspace
+}
+
+// escapeKeys are the actions of the escape sequences (without the leading escape), that the keys of terminals send
+var escapeKeys = map[string]string{
+	"[A":  "up",
+	"OA":  "up",
+	"[B":  "down",
+	"OB":  "down",
+	"[C":  "right",
+	"OC":  "right",
+	"[D":  "left",
+	"OD":  "left",
+	"[H":  "home",
+	"OH":  "home",
+	"[1~": "home",
+	"[7~": "home",
+	"[F":  "end",
+	"OF":  "end",
+	"[4~": "end",
+	"[8~": "end",
+	"[3~": "delete",
+	"[5~": "pageUp",
+	"[6~": "pageDown",
+	"[Z":  "backTab",
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// pickOptions are the flags of the picker, that every search uses
+type pickOptions struct {
+	extensions []string
+	extended   bool
+	scopes     []string
+	limit      int
+	multi      bool
+}
+
+// key is a single key press, either an action (e.g. "up") or a char, that gets typed into the query
+type key struct {
+	action string
+	char   rune
+}
+
+// pickOutcome is the response of a search, generation tells if it belongs to the current query
+type pickOutcome struct {
+	generation int
+	query      string
+	response   *bws.Response
+}
+
+// picker is the interactive search of "bws pick", that draws onto the terminal
+type picker struct {
+	term    *terminal
+	options *pickOptions
+
+	query  []rune
+	cursor int // the position inside of the query, where typed chars get inserted
+
+	results    []bws.Result
+	total      int    // the amount of results before they got cut to the limit
+	namePart   string // the part of the query of the results, that their names matched, for the highlighting
+	current    int
+	offset     int // the first result, that fits onto the screen
+	selected   map[string]bool
+	order      []bws.Result           // the selected results in the order they got selected
+	infos      map[string]fs.FileInfo // the file infos of the results, that were drawn, nil if they couldn't be read
+	searching  bool
+	incomplete bool
+
+	generation int           // counts up with every search, so outdated outcomes can be dropped
+	cancel     chan struct{} // closing it cancels the running search
+	outcomes   chan pickOutcome
+	quit       chan struct{} // closed once the picker is done, so nothing waits on it anymore
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+runPick lets the user interactively pick results and prints them, by default every positional argument makes up the initial query.
+
+Every key press searches again, after cancelling the search that was still running. Tab selects multiple results with --multi.
+Enter prints the selected results, or the current one if none were selected. Esc or ctrl-c abort with the exitAborted.
+The picker draws onto the terminal itself, so its output can be captured (e.g. vim "$(bws pick)").
+*/
+func runPick(args []string) int {
+	flags := newFlagSet("pick", "[flags] [query]...")
+	configFile := flags.String("config", "", "the path of the config file")
+	extensions := stringList{}
+	flags.Var(&extensions, "ext", "only search for these extensions or kinds, comma separated or repeated (e.g. .pdf,video)")
+	extended := flags.Bool("extended", false, "also search the extended scopes, like the secondary dirs")
+	scopes := stringList{}
+	flags.Var(&scopes, "scope", "only search these scopes, comma separated or repeated (overrides --extended)")
+	limit := flags.Int("limit", 1000, "show at most this many results, 0 means all of them")
+	multi := flags.Bool("multi", false, "select multiple results with tab and shift-tab")
+	outputValues := addOutputFlags(flags)
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	if *limit < 0 {
+		return fail(errors.New("the limit can't be negative"))
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	term, err := openTerminal()
+	if err != nil {
+		return fail(err)
+	}
+
+	// without a daemon the cache of this process gets generated right away, the picker shows the incomplete results meanwhile
+	go ensureIndex()
+
+	newPicker := picker{
+		term:     term,
+		options:  &pickOptions{extensions: extensions, extended: *extended, scopes: scopes, limit: *limit, multi: *multi},
+		query:    []rune(strings.Join(positional, " ")),
+		selected: make(map[string]bool),
+		infos:    make(map[string]fs.FileInfo),
+		outcomes: make(chan pickOutcome),
+		quit:     make(chan struct{}),
+	}
+	newPicker.cursor = len(newPicker.query)
+
+	term.output.WriteString(escAltScreen)
+	accepted, err := newPicker.run()
+	term.output.WriteString(escShowCursor + escMainScreen)
+	term.close()
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if !accepted {
+		return exitAborted
+	}
+
+	selection := newPicker.selection()
+	if len(selection) < 1 {
+		return exitNoResults
+	}
+
+	if err := writeList(out, selection, func(result bws.Result) string { return result.Path }); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// run draws the picker and handles the keys and the search outcomes, until a result got accepted or the picker got aborted
+func (picker *picker) run() (bool, error) {
+	defer close(picker.quit)
+	defer func() {
+		if picker.cancel != nil {
+			close(picker.cancel)
+		}
+	}()
+
+	keys := make(chan []key)
+	go picker.readKeys(keys)
+
+	resized := make(chan os.Signal, 1)
+	if resizeSignal != nil {
+		signal.Notify(resized, resizeSignal)
+		defer signal.Stop(resized)
+	}
+
+	picker.startSearch()
+	picker.draw()
+
+	for {
+		select {
+		case pressed, ok := <-keys:
+			if !ok {
+				return false, errors.New("couldn't read from the terminal")
+			}
+
+			for _, pressedKey := range pressed {
+				switch picker.handleKey(pressedKey) {
+				case actionAccept:
+					return true, nil
+				case actionAbort:
+					return false, nil
+				}
+			}
+		case outcome := <-picker.outcomes:
+			if outcome.generation != picker.generation {
+				continue
+			}
+
+			picker.show(outcome)
+		case <-resized:
+		}
+
+		picker.draw()
+	}
+}
+
+// readKeys sends the keys read from the terminal to the keys channel, which gets closed once the terminal can't be read anymore
+func (picker *picker) readKeys(keys chan<- []key) {
+	defer close(keys)
+
+	buffer := make([]byte, 256)
+	for {
+		count, err := picker.term.input.Read(buffer)
+		if err != nil {
+			return
+		}
+
+		select {
+		case keys <- parseKeys(buffer[:count]):
+		case <-picker.quit:
+			return
+		}
+	}
+}
+
+/*
+parseKeys turns the bytes of a single read from the terminal into keys.
+
+A lone escape is the esc key, as the sequences of other keys always arrive in one read. Alt combinations and unknown sequences are dropped.
+*/
+func parseKeys(input []byte) []key {
+	keys := []key{}
+
+	for len(input) > 0 {
+		switch {
+		case input[0] == 0x1b && len(input) == 1:
+			keys = append(keys, key{action: "abort"})
+			input = input[1:]
+		case input[0] == 0x1b && (input[1] == '[' || input[1] == 'O'):
+			// a sequence ends with its first byte between '@' and '~'
+			end := 2
+			for end < len(input) && (input[end] < 0x40 || input[end] > 0x7e) {
+				end++
+			}
+
+			if end == len(input) {
+				return keys
+			}
+
+			keys = append(keys, key{action: escapeKeys[string(input[1:end+1])]})
+			input = input[end+1:]
+		case input[0] == 0x1b:
+			_, size := utf8.DecodeRune(input[1:])
+			input = input[1+size:]
+		case input[0] < 0x20 || input[0] == 0x7f:
+			keys = append(keys, key{action: controlKeys[input[0]]})
+			input = input[1:]
+		default:
+			char, size := utf8.DecodeRune(input)
+			keys = append(keys, key{char: char})
+			input = input[size:]
+		}
+	}
+
+	return keys
+}
+
+// handleKey applies the key to the picker and returns the action, that ends the picker, or actionNone. A changed query starts a new search
+func (picker *picker) handleKey(pressedKey key) int {
+	previous := string(picker.query)
+
+	switch pressedKey.action {
+	case "enter":
+		return actionAccept
+	case "abort":
+		return actionAbort
+	case "up":
+		picker.move(-1)
+	case "down":
+		picker.move(1)
+	case "pageUp":
+		picker.move(-picker.listRows())
+	case "pageDown":
+		picker.move(picker.listRows())
+	case "tab":
+		picker.toggle()
+		picker.move(1)
+	case "backTab":
+		picker.toggle()
+		picker.move(-1)
+	case "left":
+		picker.cursor = max(picker.cursor-1, 0)
+	case "right":
+		picker.cursor = min(picker.cursor+1, len(picker.query))
+	case "home":
+		picker.cursor = 0
+	case "end":
+		picker.cursor = len(picker.query)
+	case "backspace":
+		if picker.cursor > 0 {
+			picker.query = slices.Delete(picker.query, picker.cursor-1, picker.cursor)
+			picker.cursor--
+		}
+	case "delete":
+		if picker.cursor < len(picker.query) {
+			picker.query = slices.Delete(picker.query, picker.cursor, picker.cursor+1)
+		}
+	case "clearLine":
+		picker.query = slices.Delete(picker.query, 0, picker.cursor)
+		picker.cursor = 0
+	case "deleteWord":
+		// like in a shell, the spaces before the cursor go together with the word before them
+		start := picker.cursor
+		for start > 0 && unicode.IsSpace(picker.query[start-1]) {
+			start--
+		}
+		for start > 0 && !unicode.IsSpace(picker.query[start-1]) {
+			start--
+		}
+
+		picker.query = slices.Delete(picker.query, start, picker.cursor)
+		picker.cursor = start
+	case "":
+		if unicode.IsPrint(pressedKey.char) {
+			picker.query = slices.Insert(picker.query, picker.cursor, pressedKey.char)
+			picker.cursor++
+		}
+	}
+
+	if string(picker.query) != previous {
+		picker.startSearch()
+	}
+
+	return actionNone
+}
+
+// move moves the current result by the delta, staying inside of the results
+func (picker *picker) move(delta int) {
+	picker.current = max(min(picker.current+delta, len(picker.results)-1), 0)
+}
+
+// toggle selects the current result or unselects it, if it already was selected. Without multi it does nothing
+func (picker *picker) toggle() {
+	if !picker.options.multi || len(picker.results) < 1 {
+		return
+	}
+
+	result := picker.results[picker.current]
+	if picker.selected[result.Path] {
+		delete(picker.selected, result.Path)
+		picker.order = slices.DeleteFunc(picker.order, func(element bws.Result) bool { return element.Path == result.Path })
+		return
+	}
+
+	picker.selected[result.Path] = true
+	picker.order = append(picker.order, result)
+}
+
+// selection returns the selected results, or the current one if none were selected
+func (picker *picker) selection() []bws.Result {
+	if len(picker.order) > 0 {
+		return picker.order
+	}
+
+	if len(picker.results) < 1 {
+		return []bws.Result{}
+	}
+
+	return []bws.Result{picker.results[picker.current]}
+}
+
+// startSearch cancels the running search and starts a new one for the current query
+func (picker *picker) startSearch() {
+	if picker.cancel != nil {
+		close(picker.cancel)
+	}
+
+	picker.generation++
+	picker.cancel = make(chan struct{})
+	picker.searching = true
+
+	go picker.search(picker.generation, string(picker.query), picker.cancel)
+}
+
+/*
+search runs the search for the query and sends its outcome, until it gets cancelled.
+
+If the cache is still being generated, the incomplete results get sent first and the search runs again once it's complete.
+*/
+func (picker *picker) search(generation int, query string, cancelled <-chan struct{}) {
+	// the results get cut to the limit by show, so it still knows their total
+	searchQuery := client.Query{SearchString: query, FileExtensions: picker.options.extensions, Extended: picker.options.extended, Scopes: picker.options.scopes}
+
+	for {
+		response, brokenEarly := client.SearchUntil(searchQuery, cancelled)
+		if brokenEarly {
+			return
+		}
+
+		select {
+		case picker.outcomes <- pickOutcome{generation: generation, query: query, response: response}:
+		case <-cancelled:
+			return
+		}
+
+		if !response.Incomplete {
+			return
+		}
+
+		select {
+		case <-response.Ready:
+		case <-cancelled:
+			return
+		}
+	}
+}
+
+// show replaces the results with the ones of the outcome and moves back to the best one
+func (picker *picker) show(outcome pickOutcome) {
+	picker.results = outcome.response.Results
+	picker.total = len(picker.results)
+	if picker.options.limit > 0 && len(picker.results) > picker.options.limit {
+		picker.results = picker.results[:picker.options.limit]
+	}
+
+	picker.namePart = bwssearch.NamePart(outcome.query)
+	picker.current = 0
+	picker.offset = 0
+	picker.searching = false
+	picker.incomplete = outcome.response.Incomplete
+}
+
+// listRows returns how many results fit onto the terminal
+func (picker *picker) listRows() int {
+	rows, _ := picker.term.size()
+
+	return max(rows-pickerChrome, 1)
+}
+
+/*
+draw redraws the whole picker: the prompt with the query, the status, the results and the info of the current result.
+
+Every line gets cleared after its content, so there is no flickering from clearing the screen first.
+*/
+func (picker *picker) draw() {
+	_, columns := picker.term.size()
+	listRows := picker.listRows()
+
+	// scroll just far enough, that the current result is visible
+	if picker.current < picker.offset {
+		picker.offset = picker.current
+	} else if picker.current >= picker.offset+listRows {
+		picker.offset = picker.current - listRows + 1
+	}
+
+	var screen strings.Builder
+	screen.WriteString(escHideCursor + escHome)
+
+	screen.WriteString(styleBold + "> " + styleReset + string(picker.query) + escClearLine + "\r\n")
+	screen.WriteString(styleDim + fitText(picker.status(), columns) + styleReset + escClearLine + "\r\n")
+
+	for row := range listRows {
+		if index := picker.offset + row; index < len(picker.results) {
+			screen.WriteString(picker.formatResult(picker.results[index], index == picker.current, columns))
+		}
+
+		screen.WriteString(escClearLine + "\r\n")
+	}
+
+	if len(picker.results) > 0 {
+		screen.WriteString(styleDim + fitText(formatInfo(picker.results[picker.current]), columns) + styleReset)
+	}
+	screen.WriteString(escClearLine)
+
+	// put the cursor back into the prompt, behind the "> "
+	fmt.Fprintf(&screen, "\x1b[1;%dH", picker.cursor+3)
+	screen.WriteString(escShowCursor)
+
+	picker.term.output.WriteString(screen.String())
+}
+
+// status returns the line below the prompt, with the amount of results and what the picker is waiting for
+func (picker *picker) status() string {
+	status := fmt.Sprintf("  %d", len(picker.results))
+	if picker.total > len(picker.results) {
+		status += fmt.Sprintf(" of %d", picker.total)
+	}
+
+	status += " results"
+
+	if len(picker.order) > 0 {
+		status += fmt.Sprintf(", %d selected", len(picker.order))
+	}
+
+	if picker.searching {
+		status += ", searching..."
+	} else if picker.incomplete {
+		status += ", indexing..."
+	}
+
+	return status
+}
+
+// formatResult returns the line of the result: its markers, its path with the match highlighted and its metadata on the right
+func (picker *picker) formatResult(result bws.Result, current bool, columns int) string {
+	marker := "  "
+	switch {
+	case current && picker.selected[result.Path]:
+		marker = ">*"
+	case current:
+		marker = "> "
+	case picker.selected[result.Path]:
+		marker = " *"
+	}
+
+	metadata := picker.metadata(result)
+	width := columns - len(marker) - len(metadata) - 1
+	if width < 20 {
+		metadata = ""
+		width = columns - len(marker)
+	}
+
+	start, end := matchRange(result.Path, picker.namePart)
+	path, length := fitPath(result.Path, start, end, width)
+
+	line := marker + path
+	if len(metadata) > 0 {
+		line += strings.Repeat(" ", width-length+1) + styleDim + metadata + styleReset
+	}
+
+	if current {
+		return styleBold + line + styleReset
+	}
+
+	return line
+}
+
+// metadata returns the size (or "folder") and the last modification of the result, the file infos get cached for the next draw
+func (picker *picker) metadata(result bws.Result) string {
+	info, ok := picker.infos[result.Path]
+	if !ok {
+		if len(result.Archive) < 1 {
+			info, _ = os.Stat(result.Path)
+		}
+
+		picker.infos[result.Path] = info
+	}
+
+	switch {
+	case info == nil && len(result.Archive) > 0:
+		return "in archive"
+	case info == nil && result.Broken:
+		return "broken link"
+	case info == nil:
+		return ""
+	case info.IsDir():
+		return fmt.Sprintf("%10s  %s", "folder", info.ModTime().Format("2006-01-02 15:04"))
+	}
+
+	return fmt.Sprintf("%10s  %s", formatSize(info.Size()), info.ModTime().Format("2006-01-02 15:04"))
+}
+
+// formatInfo returns the line below the results for the current result: its scope, its MIME type and the snippet, that matched its content
+func formatInfo(result bws.Result) string {
+	parts := []string{"  " + result.Scope}
+
+	if len(result.MIME) > 0 {
+		parts = append(parts, result.MIME)
+	}
+
+	if len(result.Archive) > 0 {
+		parts = append(parts, "inside of "+result.Archive)
+	}
+
+	if len(result.Snippet) > 0 {
+		parts = append(parts, "\""+strings.TrimSpace(result.Snippet)+"\"")
+	}
+
+	return strings.Join(parts, "  ")
+}
+
+/*
+matchRange returns the byte range of the path, that the namePart matched, or -1 and -1 if it can't be found.
+
+Only the last element of the path is searched, as that's the only one the search matches against.
+*/
+func matchRange(path string, namePart string) (int, int) {
+	base := filepath.Base(path)
+	lowerBase := strings.ToLower(base)
+
+	// lowering some chars changes their length, then the range of the lowered name doesn't fit the path anymore
+	if len(namePart) < 1 || len(lowerBase) != len(base) {
+		return -1, -1
+	}
+
+	index := strings.Index(lowerBase, namePart)
+	if index < 0 {
+		return -1, -1
+	}
+
+	baseStart := strings.LastIndex(path, base)
+
+	return baseStart + index, baseStart + index + len(namePart)
+}
+
+/*
+fitPath returns the path with the byte range from start to end highlighted and its length in chars.
+
+A path longer than the width gets its beginning replaced by "…", as the end of a path tells the most about it.
+Control chars get replaced by "?", so they can't mess up the terminal.
+*/
+func fitPath(path string, start int, end int, width int) (string, int) {
+	length := utf8.RuneCountInString(path)
+	if width < 1 {
+		return "", 0
+	}
+
+	var builder strings.Builder
+	if length > width {
+		cut := 0
+		for range length - width + 1 {
+			_, size := utf8.DecodeRuneInString(path[cut:])
+			cut += size
+		}
+
+		path = path[cut:]
+		start, end = max(start-cut, 0), end-cut
+		length = width
+
+		builder.WriteString("…")
+	}
+
+	highlighted := start >= 0 && end > 0
+	for index, char := range path {
+		if highlighted && index == start {
+			builder.WriteString(styleMatchStart)
+		}
+		if highlighted && index == end {
+			builder.WriteString(styleMatchEnd)
+		}
+
+		builder.WriteRune(printable(char))
+	}
+
+	if highlighted && end >= len(path) {
+		builder.WriteString(styleMatchEnd)
+	}
+
+	return builder.String(), length
+}
+
+/*
+fitText cuts the text to the width in chars.
+
+Control chars get replaced by "?", as the text can hold the names and snippets of files, that would otherwise mess up the terminal.
+*/
+func fitText(text string, width int) string {
+	text = strings.Map(printable, text)
+
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+
+	return string([]rune(text)[:max(width, 0)])
+}
+
+// printable returns "?" for a control char and every other char as it is
+func printable(char rune) rune {
+	if unicode.IsControl(char) {
+		return '?'
+	}
+
+	return char
+}
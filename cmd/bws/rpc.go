@@ -0,0 +1,35 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+
+	"github.com/skillptm/bws/pkg/rpc"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// runRPC answers the JSON-RPC 2.0 messages of pkg/rpc on stdin with stdout, until stdin gets closed
+func runRPC(args []string) int {
+	flags := newFlagSet("rpc", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	// without a daemon the cache of this process gets generated right away, so it's ready for the first search
+	go ensureIndex()
+
+	if err := rpc.Serve(os.Stdin, os.Stdout); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
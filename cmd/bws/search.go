@@ -0,0 +1,83 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/skillptm/bws"
+	"github.com/skillptm/bws/pkg/client"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+runSearch searches for the query made up of all positional arguments (e.g. "bws search holiday kind:video") and prints the results.
+
+The search runs on the daemon, if it's running. Either way it waits for the cache to be complete, so the results are never partial.
+*/
+func runSearch(args []string) int {
+	flags := newFlagSet("search", "[flags] <query>...")
+	configFile := flags.String("config", "", "the path of the config file")
+	extensions := stringList{}
+	flags.Var(&extensions, "ext", "only search for these extensions or kinds, comma separated or repeated (e.g. .pdf,video)")
+	extended := flags.Bool("extended", false, "also search the extended scopes, like the secondary dirs")
+	scopes := stringList{}
+	flags.Var(&scopes, "scope", "only search these scopes, comma separated or repeated (overrides --extended)")
+	limit := flags.Int("limit", 0, "print at most this many results, 0 means all of them")
+	outputValues := addOutputFlags(flags)
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	if *limit < 0 {
+		return fail(errors.New("the limit can't be negative"))
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	results := search(strings.Join(positional, " "), extensions, *extended, scopes)
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if err := writeList(out, results, func(result bws.Result) string { return result.Path }); err != nil {
+		return fail(err)
+	}
+
+	if len(results) < 1 {
+		return exitNoResults
+	}
+
+	return exitOK
+}
+
+// search runs the search through the provided scopes, or if there are none through the scopes picked by the extended flag, on the complete cache
+func search(query string, extensions []string, extended bool, scopes []string) []bws.Result {
+	run := func() *bws.Response {
+		if len(scopes) > 0 {
+			return client.SearchScopes(query, extensions, scopes)
+		}
+
+		return client.DetailedSearch(query, extensions, extended)
+	}
+
+	response := run()
+	if response.Incomplete {
+		<-response.Ready
+		response = run()
+	}
+
+	return response.Results
+}
@@ -0,0 +1,49 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/skillptm/bws/pkg/server"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// runServe serves the HTTP/JSON API of pkg/server on the address, until the process gets stopped
+func runServe(args []string) int {
+	flags := newFlagSet("serve", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+	address := flags.String("addr", "127.0.0.1:7700", "the address to listen on")
+	hosts := stringList{}
+	flags.Var(&hosts, "allow-host", "also accept requests for these host names, besides localhost and the address, comma separated or repeated")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	// without a daemon the cache of this process gets generated right away, so it's ready for the first search
+	go ensureIndex()
+
+	fmt.Fprintf(os.Stderr, "bws: serving on http://%s\n", *address)
+
+	// the host of the address is always allowed, so listening on a named address (e.g. a LAN IP) just works
+	if host, _, err := net.SplitHostPort(*address); err == nil && len(host) > 0 {
+		hosts = append(hosts, host)
+	}
+
+	if err := http.ListenAndServe(*address, server.NewHandler(hosts...)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fail(err)
+	}
+
+	return exitOK
+}
@@ -0,0 +1,135 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"os"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// shellScripts are the integrations of "bws pick" by the shell they're written for
+var shellScripts = map[string]string{
+	"bash": bashScript,
+	"zsh":  zshScript,
+	"fish": fishScript,
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// bashScript binds ctrl-t and alt-c for bash, the picked paths get quoted with printf %q
+const bashScript string = `# bws pick for bash, load it with: eval "$(bws shell bash)"
+
+# ctrl-t: insert the picked files and folders at the cursor
+__bws_insert() {
+  local item selected=""
+  while IFS= read -r -d '' item; do
+    selected+="$(printf '%q' "$item") "
+  done < <(bws pick --multi -0)
+
+  READLINE_LINE="${READLINE_LINE:0:$READLINE_POINT}$selected${READLINE_LINE:$READLINE_POINT}"
+  READLINE_POINT=$((READLINE_POINT + ${#selected}))
+}
+
+# bws_cd [query]: cd into the picked folder, alt-c runs it without a query
+bws_cd() {
+  local dir
+  dir="$(bws pick --ext Folder -- "$@")" && [ -n "$dir" ] && cd -- "$dir"
+}
+
+bind -x '"\C-t": __bws_insert'
+bind -x '"\ec": bws_cd'
+`
+
+// zshScript binds ctrl-t and alt-c for zsh, the picked paths get quoted with the q flag
+const zshScript string = `# bws pick for zsh, load it with: eval "$(bws shell zsh)"
+
+# ctrl-t: insert the picked files and folders at the cursor
+__bws_insert() {
+  local item
+  local -a selected
+  while IFS= read -r -d '' item; do
+    selected+=("${(q)item}")
+  done < <(bws pick --multi -0)
+
+  if (( ${#selected} )); then
+    LBUFFER+="${(j: :)selected} "
+  fi
+  zle reset-prompt
+}
+
+# bws_cd [query]: cd into the picked folder, alt-c runs it without a query
+bws_cd() {
+  local dir
+  dir="$(bws pick --ext Folder -- "$@")" && [[ -n $dir ]] && cd -- "$dir"
+}
+
+__bws_cd_widget() {
+  bws_cd
+  zle reset-prompt
+}
+
+zle -N __bws_insert
+zle -N __bws_cd_widget
+bindkey '^T' __bws_insert
+bindkey '\ec' __bws_cd_widget
+`
+
+// fishScript binds ctrl-t and alt-c for fish, the picked paths get quoted with string escape
+const fishScript string = `# bws pick for fish, load it with: bws shell fish | source
+
+# ctrl-t: insert the picked files and folders at the cursor
+function __bws_insert
+    set -l selected (bws pick --multi)
+    if test (count $selected) -gt 0
+        commandline -it -- (string join ' ' (string escape -- $selected))' '
+    end
+    commandline -f repaint
+end
+
+# bws_cd [query]: cd into the picked folder, alt-c runs it without a query
+function bws_cd
+    set -l dir (bws pick --ext Folder -- $argv)
+    and test -n "$dir"
+    and cd -- $dir
+end
+
+function __bws_cd_widget
+    bws_cd
+    commandline -f repaint
+end
+
+bind \ct __bws_insert
+bind \ec __bws_cd_widget
+`
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+runShell prints the integration of "bws pick" for the shell, which binds ctrl-t to insert the picked paths at the cursor
+and alt-c to cd into the picked folder. It also defines bws_cd, which takes a query.
+*/
+func runShell(args []string) int {
+	flags := newFlagSet("shell", "<bash|zsh|fish>")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	script, ok := shellScripts[positional[0]]
+	if !ok {
+		return fail(fmt.Errorf("unknown shell '%s', use bash, zsh or fish", positional[0]))
+	}
+
+	fmt.Fprint(os.Stdout, script)
+
+	return exitOK
+}
@@ -0,0 +1,69 @@
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+
+	"github.com/skillptm/bws"
+	"github.com/skillptm/bws/pkg/client"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// runStats generates the cache, if that hasn't happened yet, and prints the stats of all its scopes
+func runStats(args []string) int {
+	flags := newFlagSet("stats", "[flags]")
+	configFile := flags.String("config", "", "the path of the config file")
+	outputValues := addOutputFlags(flags)
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return exitCode(err)
+	}
+
+	out, err := newOutput(outputValues)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		return fail(err)
+	}
+
+	ensureIndex()
+
+	return printStats(out, client.Stats())
+}
+
+// printStats writes the stats to the out and returns the exit code
+func printStats(out *output, stats []bws.ScopeStats) int {
+	if err := writeList(out, stats, formatScopeStats); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
+
+// formatScopeStats returns the ScopeStats as a single line
+func formatScopeStats(stats bws.ScopeStats) string {
+	if !stats.Ready {
+		return fmt.Sprintf("%s: not crawled yet", stats.Scope)
+	}
+
+	line := fmt.Sprintf("%s: %d files, %d folders, %s", stats.Scope, stats.Files, stats.Folders, formatSize(stats.Size))
+
+	if stats.ArchiveMembers > 0 {
+		line += fmt.Sprintf(", %d archive members", stats.ArchiveMembers)
+	}
+
+	if stats.BrokenLinks > 0 {
+		line += fmt.Sprintf(", %d broken links", stats.BrokenLinks)
+	}
+
+	if stats.Errors > 0 {
+		line += fmt.Sprintf(", %d unreadable folders", stats.Errors)
+	}
+
+	return line + fmt.Sprintf(" (updated %s)", stats.LastUpdate.Format("2006-01-02 15:04:05"))
+}
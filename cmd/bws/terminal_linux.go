@@ -0,0 +1,17 @@
+//go:build linux
+
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"syscall"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	ioctlGetTermios uintptr = syscall.TCGETS
+	ioctlSetTermios uintptr = syscall.TCSETS
+)
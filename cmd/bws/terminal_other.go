@@ -0,0 +1,39 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"os"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// resizeSignal is nil, as there is no signal for a changed size of the terminal
+var resizeSignal os.Signal
+
+// <---------------------------------------------------------------------------------------------------->
+
+// terminal is never opened on this system
+type terminal struct {
+	input  *os.File
+	output *os.File
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// openTerminal always fails, raw mode is only supported on linux, the BSDs (including macOS) and windows
+func openTerminal() (*terminal, error) {
+	return nil, errors.New("bws pick isn't supported on this system")
+}
+
+// size returns the default size of 24 by 80
+func (term *terminal) size() (int, int) {
+	return 24, 80
+}
+
+// close does nothing
+func (term *terminal) close() {}
@@ -0,0 +1,91 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// resizeSignal is the signal the terminal sends, once its size changed
+var resizeSignal os.Signal = syscall.SIGWINCH
+
+// <---------------------------------------------------------------------------------------------------->
+
+// terminal is the controlling terminal of the process in raw mode, so it can be drawn on, even if stdin and stdout are redirected
+type terminal struct {
+	input    *os.File
+	output   *os.File        // the same file as the input
+	original syscall.Termios // the mode the terminal had before, which close restores
+}
+
+// windowSize is the struct the TIOCGWINSZ ioctl fills
+type windowSize struct {
+	rows    uint16
+	columns uint16
+	xPixels uint16
+	yPixels uint16
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// openTerminal opens the controlling terminal and puts it into raw mode, so every key gets read right away without being echoed
+func openTerminal() (*terminal, error) {
+	file, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the terminal; %s", err.Error())
+	}
+
+	term := terminal{input: file, output: file}
+	if err := ioctl(file.Fd(), ioctlGetTermios, unsafe.Pointer(&term.original)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("couldn't read the mode of the terminal; %s", err.Error())
+	}
+
+	raw := term.original
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(file.Fd(), ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("couldn't put the terminal into raw mode; %s", err.Error())
+	}
+
+	return &term, nil
+}
+
+// size returns the rows and columns of the terminal, or 24 by 80 if they can't be read
+func (term *terminal) size() (int, int) {
+	size := windowSize{}
+	if err := ioctl(term.input.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil || size.rows < 1 || size.columns < 1 {
+		return 24, 80
+	}
+
+	return int(size.rows), int(size.columns)
+}
+
+// close restores the mode the terminal had before and closes it
+func (term *terminal) close() {
+	ioctl(term.input.Fd(), ioctlSetTermios, unsafe.Pointer(&term.original))
+	term.input.Close()
+}
+
+// ioctl runs the ioctl request on the fd with the pointer as its argument
+func ioctl(fd uintptr, request uintptr, pointer unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(pointer)); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
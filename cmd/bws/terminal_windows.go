@@ -0,0 +1,135 @@
+//go:build windows
+
+// Package main is the bws command line tool, that searches and manages the cache of the bws module.
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	enableProcessedInput       uint32 = 0x0001 // ENABLE_PROCESSED_INPUT, the console handles ctrl+c itself
+	enableLineInput            uint32 = 0x0002 // ENABLE_LINE_INPUT, reads only return once enter was pressed
+	enableEchoInput            uint32 = 0x0004 // ENABLE_ECHO_INPUT, the keys get printed as they're typed
+	enableVirtualTerminalInput uint32 = 0x0200 // ENABLE_VIRTUAL_TERMINAL_INPUT, keys like the arrows are read as escape sequences
+
+	enableProcessedOutput       uint32 = 0x0001 // ENABLE_PROCESSED_OUTPUT, control chars like "\r" and "\n" move the cursor
+	enableVirtualTerminalOutput uint32 = 0x0004 // ENABLE_VIRTUAL_TERMINAL_PROCESSING, escape sequences get drawn instead of printed
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// resizeSignal is nil, as windows has no signal for a changed size of the console, the new size gets used on the next key instead
+var resizeSignal os.Signal
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// terminal is the console of the process with raw input and escape sequences turned on, so it can be drawn on, even if stdin and stdout are redirected
+type terminal struct {
+	input          *os.File
+	output         *os.File
+	originalInput  uint32 // the mode the input had before, which close restores
+	originalOutput uint32 // the mode the output had before, which close restores
+}
+
+// screenBufferInfo is the CONSOLE_SCREEN_BUFFER_INFO struct GetConsoleScreenBufferInfo fills
+type screenBufferInfo struct {
+	size              [2]int16
+	cursorPosition    [2]int16
+	attributes        uint16
+	window            [4]int16 // the left, top, right and bottom of the visible part of the buffer
+	maximumWindowSize [2]int16
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// openTerminal opens the console and puts it into raw mode, so every key gets read right away without being echoed
+func openTerminal() (*terminal, error) {
+	input, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the console; %s", err.Error())
+	}
+
+	output, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		input.Close()
+		return nil, fmt.Errorf("couldn't open the console; %s", err.Error())
+	}
+
+	term := terminal{input: input, output: output}
+	if err := syscall.GetConsoleMode(syscall.Handle(input.Fd()), &term.originalInput); err != nil {
+		term.closeFiles()
+		return nil, fmt.Errorf("couldn't read the mode of the console; %s", err.Error())
+	}
+
+	if err := syscall.GetConsoleMode(syscall.Handle(output.Fd()), &term.originalOutput); err != nil {
+		term.closeFiles()
+		return nil, fmt.Errorf("couldn't read the mode of the console; %s", err.Error())
+	}
+
+	rawInput := term.originalInput&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(input, rawInput); err != nil {
+		term.closeFiles()
+		return nil, fmt.Errorf("couldn't put the console into raw mode; %s", err.Error())
+	}
+
+	// consoles before windows 10 can't draw escape sequences, so the picker can't run on them
+	if err := setConsoleMode(output, term.originalOutput|enableProcessedOutput|enableVirtualTerminalOutput); err != nil {
+		setConsoleMode(input, term.originalInput)
+		term.closeFiles()
+		return nil, fmt.Errorf("couldn't turn on the escape sequences of the console; %s", err.Error())
+	}
+
+	return &term, nil
+}
+
+// size returns the rows and columns of the visible part of the console, or 24 by 80 if they can't be read
+func (term *terminal) size() (int, int) {
+	info := screenBufferInfo{}
+	if result, _, _ := procGetConsoleScreenBufferInfo.Call(term.output.Fd(), uintptr(unsafe.Pointer(&info))); result == 0 {
+		return 24, 80
+	}
+
+	rows, columns := int(info.window[3]-info.window[1])+1, int(info.window[2]-info.window[0])+1
+	if rows < 1 || columns < 1 {
+		return 24, 80
+	}
+
+	return rows, columns
+}
+
+// close restores the modes the console had before and closes it
+func (term *terminal) close() {
+	setConsoleMode(term.input, term.originalInput)
+	setConsoleMode(term.output, term.originalOutput)
+	term.closeFiles()
+}
+
+// closeFiles closes the input and output of the console
+func (term *terminal) closeFiles() {
+	term.input.Close()
+	term.output.Close()
+}
+
+// setConsoleMode sets the mode of the console file
+func setConsoleMode(file *os.File, mode uint32) error {
+	if result, _, err := procSetConsoleMode.Call(file.Fd(), uintptr(mode)); result == 0 {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+/*
+Package main is libbws, the C shared library of bws, for programs that can't import Go (e.g. a C++ UI or Python through ctypes).
+
+Build it with "go build -buildmode=c-shared -o libbws.so ./cmd/libbws", which also writes the header libbws.h.
+Every function that returns a char* returns a JSON string, that has to be freed with bws_free. Failures are an object
+with an "error" (and "cancelled" for searches, that got cancelled). Like pkg/client everything runs on the bws daemon,
+if it's running, otherwise on the cache of the process, that loaded the library.
+*/
+package main
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/skillptm/bws/pkg/client"
+	"github.com/skillptm/bws/pkg/options"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// errorBody is the JSON of every failure
+type errorBody struct {
+	Error     string `json:"error"`
+	Cancelled bool   `json:"cancelled,omitempty"`
+}
+
+/*
+searchParams are the JSON params of bws_search. If Scopes are provided they get searched, otherwise Extended decides.
+
+With Wait set, a search on an incomplete cache waits for it to be complete and then runs again, so its results are never partial.
+*/
+type searchParams struct {
+	Query    string   `json:"query"`
+	Ext      []string `json:"ext"`
+	Extended bool     `json:"extended"`
+	Scopes   []string `json:"scopes"`
+	Limit    int      `json:"limit"`
+	Wait     bool     `json:"wait"`
+}
+
+// searcher runs searches, that can be cancelled together with bws_cancel
+type searcher struct {
+	mutex     sync.Mutex
+	cancelled chan struct{} // gets closed by bws_cancel, to stop the searches that are running right now
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+var (
+	searchersMutex sync.Mutex
+	searchers      = make(map[int64]*searcher) // the searchers by the handles C knows them by
+	lastHandle     int64
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// main is never called, but a c-shared build needs a main package
+func main() {}
+
+// bws_searcher_new creates a searcher and returns its handle, which is never 0
+//
+//export bws_searcher_new
+func bws_searcher_new() C.longlong {
+	searchersMutex.Lock()
+	defer searchersMutex.Unlock()
+
+	lastHandle++
+	searchers[lastHandle] = &searcher{cancelled: make(chan struct{})}
+
+	return C.longlong(lastHandle)
+}
+
+// bws_searcher_free cancels the searches of the searcher and destroys it, its handle can't be used anymore
+//
+//export bws_searcher_free
+func bws_searcher_free(handle C.longlong) {
+	searchersMutex.Lock()
+	defer searchersMutex.Unlock()
+
+	if oldSearcher, ok := searchers[int64(handle)]; ok {
+		oldSearcher.cancel()
+		delete(searchers, int64(handle))
+	}
+}
+
+/*
+bws_search runs the search of the JSON params (see searchParams) with the searcher and returns the Response as JSON.
+
+It blocks until the search is done, so bws_cancel has to be called from another thread.
+*/
+//export bws_search
+func bws_search(handle C.longlong, params *C.char) *C.char {
+	currentSearcher, err := lookup(handle)
+	if err != nil {
+		return failure(errorBody{Error: err.Error()})
+	}
+
+	searchOptions := searchParams{}
+	if params != nil {
+		if err := json.Unmarshal([]byte(C.GoString(params)), &searchOptions); err != nil {
+			return failure(errorBody{Error: fmt.Sprintf("couldn't parse the params; %s", err.Error())})
+		}
+	}
+
+	if searchOptions.Limit < 0 {
+		return failure(errorBody{Error: "the limit can't be negative"})
+	}
+
+	cancelled := currentSearcher.current()
+	stopped := errorBody{Error: "the search was cancelled", Cancelled: true}
+
+	query := client.Query{SearchString: searchOptions.Query, FileExtensions: searchOptions.Ext, Extended: searchOptions.Extended, Scopes: searchOptions.Scopes, Limit: searchOptions.Limit}
+
+	response, brokenEarly := client.SearchUntil(query, cancelled)
+	if brokenEarly {
+		return failure(stopped)
+	}
+
+	if searchOptions.Wait && response.Incomplete {
+		select {
+		case <-response.Ready:
+		case <-cancelled:
+			return failure(stopped)
+		}
+
+		if response, brokenEarly = client.SearchUntil(query, cancelled); brokenEarly {
+			return failure(stopped)
+		}
+	}
+
+	return toJSON(response)
+}
+
+// bws_cancel cancels the searches of the searcher, that are running right now. Later searches aren't affected
+//
+//export bws_cancel
+func bws_cancel(handle C.longlong) {
+	if currentSearcher, err := lookup(handle); err == nil {
+		currentSearcher.cancel()
+	}
+}
+
+// bws_status returns the IndexStatus as JSON
+//
+//export bws_status
+func bws_status() *C.char {
+	return toJSON(client.GetIndexStatus())
+}
+
+/*
+bws_set_options applies the JSON config (with the keys of a config file, see pkg/options.File) and returns an empty object,
+or the error of every key, that couldn't be applied. The options only apply to the cache of this process, not to the daemon.
+*/
+//export bws_set_options
+func bws_set_options(config *C.char) *C.char {
+	if config == nil {
+		return failure(errorBody{Error: "the config is missing"})
+	}
+
+	file, err := options.ParseFile([]byte(C.GoString(config)))
+	if err != nil {
+		return failure(errorBody{Error: err.Error()})
+	}
+
+	if err := file.Apply(); err != nil {
+		return failure(errorBody{Error: err.Error()})
+	}
+
+	return toJSON(struct{}{})
+}
+
+// bws_free frees a string returned by any of the other functions
+//
+//export bws_free
+func bws_free(text *C.char) {
+	C.free(unsafe.Pointer(text))
+}
+
+// lookup returns the searcher of the handle, or an error if there is none
+func lookup(handle C.longlong) (*searcher, error) {
+	searchersMutex.Lock()
+	defer searchersMutex.Unlock()
+
+	foundSearcher, ok := searchers[int64(handle)]
+	if !ok {
+		return nil, fmt.Errorf("there is no searcher with the handle %d", int64(handle))
+	}
+
+	return foundSearcher, nil
+}
+
+// current returns the channel, that gets closed once the searches running right now get cancelled
+func (searcher *searcher) current() <-chan struct{} {
+	searcher.mutex.Lock()
+	defer searcher.mutex.Unlock()
+
+	return searcher.cancelled
+}
+
+// cancel closes the channel of the running searches and replaces it, so later searches aren't cancelled
+func (searcher *searcher) cancel() {
+	searcher.mutex.Lock()
+	defer searcher.mutex.Unlock()
+
+	close(searcher.cancelled)
+	searcher.cancelled = make(chan struct{})
+}
+
+// toJSON returns the value as a JSON string allocated by C, that has to be freed with bws_free
+func toJSON(value any) *C.char {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return failure(errorBody{Error: err.Error()})
+	}
+
+	return C.CString(string(encoded))
+}
+
+// failure returns the errorBody as a JSON string allocated by C
+func failure(body errorBody) *C.char {
+	encoded, _ := json.Marshal(body)
+
+	return C.CString(string(encoded))
+}
@@ -0,0 +1,203 @@
+// Package bws contains the main Search function and start up logic of bws.
+package bws
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"strings"
+	"time"
+
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+	"github.com/skillptm/bws/internal/util"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	ErrorPermission string = cache.ErrorPermission // we aren't allowed to read the folder
+	ErrorNotExist   string = cache.ErrorNotExist   // the folder vanished, or a root doesn't exist
+	ErrorTimeout    string = cache.ErrorTimeout    // reading the folder took longer than the ReadTimeout
+	ErrorOther      string = cache.ErrorOther      // any other error while reading the folder
+
+	CauseError        string = "error"         // a folder above the path couldn't be read
+	CauseExcluded     string = "excluded"      // the path or a folder above it was left out of the cache
+	CauseOutsideScope string = "outside scope" // the path isn't inside the roots of any scope
+	CauseNotCrawled   string = "not crawled"   // the scope hasn't been fully crawled yet
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// CrawlError is a folder that couldn't be read while crawling a scope, Kind is one of the Error constants and Err the error message itself
+type CrawlError struct {
+	Scope string
+	Path  string
+	Kind  string
+	Err   string
+	Time  time.Time
+}
+
+/*
+MissCause is a reason why a path is missing from the cache.
+
+Kind is one of the Cause constants, Path the folder (or the path itself) that caused it and Reason explains it further,
+e.g. the error message or why the folder was excluded.
+*/
+type MissCause struct {
+	Scope  string
+	Path   string
+	Kind   string
+	Reason string
+}
+
+// DebugReport tells if a path is in the cache, which scopes it belongs to and what kept it out of the cache
+type DebugReport struct {
+	Path   string
+	Cached bool
+	Scopes []string
+	Causes []MissCause
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+CrawlErrors returns all folders inside of the provided scopes, that couldn't be read during the last crawl of their scope.
+If no scopes are provided, the errors of all scopes are returned.
+
+The whole subtree of such a folder is missing from the cache.
+*/
+func CrawlErrors(scopes []string) []CrawlError {
+	fs := cache.EntrieFilesystem()
+	crawlErrors := []CrawlError{}
+
+	if len(scopes) < 1 {
+		scopes = config.BWSConfig.SearchScopes(true)
+	}
+
+	fs.RLock()
+	defer fs.RUnlock()
+
+	for _, scope := range scopes {
+		scopeCache, ok := fs.Scopes[scope]
+		if !ok {
+			continue
+		}
+
+		for _, crawlError := range scopeCache.Errors {
+			crawlErrors = append(crawlErrors, CrawlError{
+				Scope: scope,
+				Path:  crawlError.Path,
+				Kind:  crawlError.Kind,
+				Err:   crawlError.Err,
+				Time:  crawlError.Time,
+			})
+		}
+	}
+
+	return crawlErrors
+}
+
+/*
+GetDebugReport explains why a path can or can't be found.
+
+For every scope, whose roots contain the path, it checks if the path or any folder above it was excluded or couldn't be read.
+A file left out by an ignore file can't be explained, as only the folders left out are recorded.
+The path doesn't have to exist, so you can also ask for entries that were expected, but never showed up.
+*/
+func GetDebugReport(path string) *DebugReport {
+	fs := cache.EntrieFilesystem()
+	filePath := util.FormatEntry(path, false)
+	dirPath := util.FormatEntry(path, true)
+
+	report := DebugReport{Path: filePath, Scopes: []string{}, Causes: []MissCause{}}
+
+	fs.RLock()
+	defer fs.RUnlock()
+
+	for _, scope := range config.BWSConfig.Scopes {
+		for _, root := range scope.Roots {
+			if !strings.HasPrefix(dirPath, root) {
+				continue
+			}
+
+			report.Scopes = append(report.Scopes, scope.Name)
+
+			scopeCache, ok := fs.Scopes[scope.Name]
+			if !ok || !scopeCache.Ready {
+				report.Causes = append(report.Causes, MissCause{Scope: scope.Name, Path: root, Kind: CauseNotCrawled, Reason: "the scope hasn't been fully crawled yet"})
+			}
+
+			if !ok {
+				break
+			}
+
+			report.Cached = report.Cached || cached(scopeCache, filePath, dirPath)
+			report.Causes = append(report.Causes, missCauses(scope.Name, scopeCache, root, filePath)...)
+
+			break
+		}
+	}
+
+	if len(report.Scopes) < 1 {
+		report.Causes = append(report.Causes, MissCause{Path: filePath, Kind: CauseOutsideScope, Reason: "the path isn't inside the roots of any scope"})
+	}
+
+	return &report
+}
+
+// cached checks if the path is inside the entries of the scopeCache, as either a file or a folder
+func cached(scopeCache *cache.ScopeCache, filePath string, dirPath string) bool {
+	for _, lengthMaps := range scopeCache.Entries {
+		for _, entries := range lengthMaps {
+			for _, entry := range entries {
+				if entry.Path == filePath || entry.Path == dirPath {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// missCauses checks the path and every folder above it (up to the root) for exclusions and errors of the scopeCache
+func missCauses(scopeName string, scopeCache *cache.ScopeCache, root string, filePath string) []MissCause {
+	causes := []MissCause{}
+
+	crawlErrors := make(map[string]cache.CrawlError)
+	for _, crawlError := range scopeCache.Errors {
+		crawlErrors[crawlError.Path] = crawlError
+	}
+
+	// the root itself, every folder below it and finally the path as a file and as a folder
+	candidates := []string{root}
+	for index := len(root); index < len(filePath); index++ {
+		if filePath[index] == '/' {
+			candidates = append(candidates, filePath[:index+1])
+		}
+	}
+	candidates = append(candidates, filePath, util.FormatEntry(filePath, true))
+
+	checked := make(map[string]bool)
+	for _, candidate := range candidates {
+		if checked[candidate] {
+			continue
+		}
+		checked[candidate] = true
+
+		if reason, ok := scopeCache.Excluded[candidate]; ok {
+			causes = append(causes, MissCause{Scope: scopeName, Path: candidate, Kind: CauseExcluded, Reason: reason})
+		}
+
+		if crawlError, ok := crawlErrors[candidate]; ok {
+			causes = append(causes, MissCause{Scope: scopeName, Path: candidate, Kind: CauseError, Reason: crawlError.Err})
+		}
+	}
+
+	// excluded files only get counted, so the exclude patterns have to be checked for the path itself
+	if len(config.BWSConfig.ExcludeRules) > 0 && config.BWSConfig.ExcludeRules.Excluded(strings.TrimPrefix(filePath, root), false) {
+		causes = append(causes, MissCause{Scope: scopeName, Path: filePath, Kind: CauseExcluded, Reason: cache.ExcludedByPattern})
+	}
+
+	return causes
+}
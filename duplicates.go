@@ -0,0 +1,94 @@
+// Package bws contains the main Search function and start up logic of bws.
+package bws
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+	"github.com/skillptm/bws/internal/duplicate"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+DuplicateOptions are the settings for FindDuplicates.
+
+Files smaller than MinSize are ignored and Workers sets how many files get hashed at once, if it's 0 the CPUThreads of the config are used.
+With SameName turned on, the sets hold files with the same name but different contents instead of files with the same content.
+*/
+type DuplicateOptions = duplicate.Options
+
+/*
+DuplicateSet is a group of files with the same content and the bytes they waste, as only one of them would be needed.
+
+In the SameName mode, it's a group of files with the same Name but different contents, its Size is the size of all of them together.
+*/
+type DuplicateSet = duplicate.Set
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+FindDuplicates returns the sets of files with the same content inside of the scope, ranked by the bytes they waste.
+
+Only files with the same size get hashed, first over their start and end and only if those match over their whole content.
+If the cache hasn't been generated yet, this waits until it's done, so the sets are always complete.
+*/
+func FindDuplicates(scope string, options DuplicateOptions) ([]DuplicateSet, error) {
+	sets, _, err := GoFindDuplicatesWithBreak(scope, options, make(chan bool, 1)) // insert a dummy channel, as it's not needed here
+	return sets, err
+}
+
+/*
+GoFindDuplicatesWithBreak behaves exactly like FindDuplicates, the only difference is, it requires a break channel as an input.
+
+This function should be started as a goroutine and it can be cancelled early by sending something in the breakChan.
+If it breaks early, it returns a true, otherwise false.
+*/
+func GoFindDuplicatesWithBreak(scope string, options DuplicateOptions, breakChan chan bool) ([]DuplicateSet, bool, error) {
+	if config.BWSConfig.Scope(scope) == nil {
+		return []DuplicateSet{}, false, fmt.Errorf("there is no scope called '%s'", scope)
+	}
+
+	if options.Workers < 1 {
+		options.Workers = max(config.BWSConfig.CPUThreads, 1)
+	}
+
+	forceStopChan := make(chan bool, 1)
+	go func() {
+		// check for when we receive the break signal
+		for range breakChan {
+			// send something into the forceStopChan to stop the hashing
+			forceStopChan <- true
+		}
+	}()
+
+	// the sizes are only complete once the scope has been crawled, so we wait for the cache
+	fs := cache.EntrieFilesystem()
+	if !fs.SetupProperly.Load() {
+		fs = cache.Generate(config.BWSConfig.Scopes)
+	}
+
+	for waiting := true; waiting; {
+		select {
+		case <-fs.Ready():
+			waiting = false
+		case <-time.After(100 * time.Millisecond):
+			// check if we have to stop while waiting
+			if len(forceStopChan) > 0 {
+				return []DuplicateSet{}, true, nil
+			}
+		}
+	}
+
+	// make it so while we hash the files we can't update the FileSystem
+	fs.Updateable.Store(false)
+	defer fs.Updateable.Store(true)
+
+	sets, stopped := duplicate.Find(fs, scope, options, forceStopChan)
+
+	return sets, stopped, nil
+}
@@ -0,0 +1,280 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	ArchiveSeparator string = "!/" // separates the path of an archive from the path of a member inside of it
+)
+
+// archiveExtensions are the extensions of the archives, whose members can be listed
+var archiveExtensions = map[string]bool{
+	".zip":    true,
+	".tar":    true,
+	".tar.gz": true,
+	".tgz":    true,
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// archiveMember is a single file or folder inside of an archive
+type archiveMember struct {
+	name    string // the path inside of the archive, separated by "/" and without a leading or trailing "/"
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+// archiveRecord are the members of an archive, together with the size and modification time it had back then
+type archiveRecord struct {
+	members []archiveMember
+	size    int64
+	modTime time.Time
+}
+
+/*
+archiver lists the members of archives during a crawl.
+
+Archives that haven't changed since the previous crawl keep their members without being read again.
+All other archives wait for the rateLimiter, just like the sniffing of MIME types.
+*/
+type archiver struct {
+	limiter    *rateLimiter
+	maxSize    int64
+	maxMembers int
+	previous   map[string]archiveRecord
+
+	mutex   sync.Mutex
+	records map[string]archiveRecord
+}
+
+// newArchiver returns a pointer to an archiver, that reuses the previous records
+func newArchiver(previous map[string]archiveRecord, limiter *rateLimiter, maxSize int64, maxMembers int) *archiver {
+	if previous == nil {
+		previous = make(map[string]archiveRecord)
+	}
+
+	return &archiver{
+		limiter:    limiter,
+		maxSize:    maxSize,
+		maxMembers: maxMembers,
+		previous:   previous,
+		records:    make(map[string]archiveRecord),
+	}
+}
+
+// members returns the entries of all members of the archive at archivePath, if it isn't larger than the maxSize
+func (a *archiver) members(archivePath string, extension string, info os.FileInfo) []*Entry {
+	entries := []*Entry{}
+
+	if !archiveExtensions[extension] || info.Size() > a.maxSize {
+		return entries
+	}
+
+	record, ok := a.previous[archivePath]
+	if !ok || record.size != info.Size() || !record.modTime.Equal(info.ModTime()) {
+		a.limiter.wait()
+
+		members, err := listArchive(archivePath, extension, a.maxMembers)
+		if err != nil {
+			return entries
+		}
+
+		record = archiveRecord{members: members, size: info.Size(), modTime: info.ModTime()}
+	}
+
+	a.mutex.Lock()
+	a.records[archivePath] = record
+	a.mutex.Unlock()
+
+	for _, member := range record.members {
+		memberPath := archivePath + ArchiveSeparator + member.name
+		if member.isDir {
+			memberPath += "/"
+		}
+
+		newMember := newEntry(memberPath, path.Base(member.name), member.isDir)
+		newMember.Archive = archivePath
+		newMember.Size = member.size
+		newMember.ModTime = member.modTime
+		entries = append(entries, newMember)
+	}
+
+	return entries
+}
+
+// listArchive reads up to maxMembers members from the archive at archivePath
+func listArchive(archivePath string, extension string, maxMembers int) ([]archiveMember, error) {
+	if extension == ".zip" {
+		return listZip(archivePath, maxMembers)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if extension != ".tar" {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	return listTar(reader, maxMembers)
+}
+
+// listZip reads up to maxMembers members from the central directory of the zip file at archivePath
+func listZip(archivePath string, maxMembers int) ([]archiveMember, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	members := []archiveMember{}
+
+	for _, file := range reader.File {
+		if len(members) >= maxMembers {
+			break
+		}
+
+		if name, ok := memberName(file.Name); ok {
+			members = append(members, archiveMember{name: name, isDir: file.FileInfo().IsDir(), size: int64(file.UncompressedSize64), modTime: file.Modified})
+		}
+	}
+
+	return members, nil
+}
+
+// listTar reads up to maxMembers members from the headers of the tar stream
+func listTar(reader io.Reader, maxMembers int) ([]archiveMember, error) {
+	tarReader := tar.NewReader(reader)
+	members := []archiveMember{}
+
+	for len(members) < maxMembers {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// a truncated archive still lists the members we got so far
+			return members, nil
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			continue
+		}
+
+		if name, ok := memberName(header.Name); ok {
+			members = append(members, archiveMember{name: name, isDir: header.Typeflag == tar.TypeDir, size: header.Size, modTime: header.ModTime})
+		}
+	}
+
+	return members, nil
+}
+
+// memberName cleans up the name of a member, or returns false if it doesn't name anything (e.g. "./")
+func memberName(name string) (string, bool) {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))[1:]
+
+	return name, len(name) > 0
+}
+
+// archiveRecords returns the archive records of the last crawl of the scope
+func (fs *Filesystem) archiveRecords(scopeName string) map[string]archiveRecord {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		return scopeCache.archives
+	}
+
+	return nil
+}
+
+// setArchiveRecords stores the archive records of a crawl in the scope of the fs, so the next crawl can reuse them
+func (fs *Filesystem) setArchiveRecords(scopeName string, records map[string]archiveRecord) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		scopeCache.archives = records
+	}
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// memberInfo is the fs.FileInfo of an archive member, as it can't be read with os.Stat
+type memberInfo struct {
+	entry *Entry
+}
+
+// Info returns the fs.FileInfo of the entry, for archive members it's built from the archive, for everything else it's read with os.Stat
+func (entry *Entry) Info() (fs.FileInfo, error) {
+	if len(entry.Archive) > 0 {
+		return memberInfo{entry: entry}, nil
+	}
+
+	info, err := os.Stat(entry.Path)
+	if err != nil && entry.IsLink {
+		// a broken link still exists, so we use the info of the link itself
+		info, err = os.Lstat(entry.Path)
+	}
+
+	return info, err
+}
+
+// Name returns the base name of the member, without the trailing "/" of a folder
+func (info memberInfo) Name() string {
+	return path.Base(strings.TrimSuffix(info.entry.Path, "/"))
+}
+
+// Size returns the uncompressed size of the member, as it was stored in the archive
+func (info memberInfo) Size() int64 {
+	return info.entry.Size
+}
+
+// ModTime returns the modification time of the member, as it was stored in the archive
+func (info memberInfo) ModTime() time.Time {
+	return info.entry.ModTime
+}
+
+// IsDir reports whether the member is a folder
+func (info memberInfo) IsDir() bool {
+	return info.entry.Extension == "Folder"
+}
+
+// Sys always returns nil, as the member has no data of the underlying system
+func (info memberInfo) Sys() any {
+	return nil
+}
+
+// Mode returns read only permissions, as members can't be written through the cache
+func (info memberInfo) Mode() fs.FileMode {
+	if info.IsDir() {
+		return fs.ModeDir | 0o555
+	}
+
+	return 0o444
+}
@@ -4,192 +4,360 @@ package cache
 // <---------------------------------------------------------------------------------------------------->
 
 import (
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/skillptm/ssl/pkg/sslslices"
-
 	"github.com/skillptm/bws/internal/config"
-	"github.com/skillptm/bws/internal/util"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	addBatchSize int = 1000 // entries that get added at once, while a Filesystem is being generated
 )
 
 // <---------------------------------------------------------------------------------------------------->
 
 var (
-	EntrieFilesystem *Filesystem = &Filesystem{SetupProperly: false}
+	entrieFilesystem atomic.Pointer[Filesystem] // the Filesystem that gets searched, replaced by every Generate call
+	generateMutex    sync.Mutex
 )
 
 // <---------------------------------------------------------------------------------------------------->
 
+// Entry is a single cached file or folder
+type Entry struct {
+	Path      string
+	Name      string            // the lower case name without the extension
+	FullName  string            // the lower case name with the extension
+	Extension string            // the lower case extension with its leading ".", or "File"/"Folder" if there is none
+	Encoded   [8]byte           // the encoded FullName, so it fits searches with and without the extension
+	MIME      string            // the sniffed MIME type, only set if SniffMIME is turned on
+	Archive   string            // the path of the archive the entry is a member of, empty for everything else
+	Fields    map[string]string // the fields read by the extractors (e.g. "artist" or "taken")
+	Size      int64             // the size of files in bytes, 0 for folders
+	ModTime   time.Time         // the last modification of files, unset for folders
+	IsLink    bool
+	Broken    bool // the entry is a link, whose target doesn't exist
+}
+
+/*
+ScopeCache holds the cached entries of a single scope, sorted by their extension and then the length of their name.
+
+Errors holds the folders that couldn't be read and Excluded the folders that were left out (with the reason why) during the last crawl.
+ExcludedCounts counts all entries, that were left out, files included, by the reason why.
+*/
+type ScopeCache struct {
+	Entries         map[string]map[int][]*Entry
+	FieldExtensions map[string]bool         // the extensions that have at least one entry with metadata fields
+	Folders         map[string]*FolderUsage // the size, amount and newest modification of the files below every folder by its path
+	BrokenLinks     []string
+	Errors          []CrawlError
+	Excluded        map[string]string
+	ExcludedCounts  map[string]int
+	Ready           bool // the scope has been fully crawled at least once
+	LastUpdate      time.Time
+
+	mimes    map[string]mimeRecord    // the sniffed MIME types by path, so unchanged files don't get read again
+	contents map[string]contentRecord // the words of the text files by path, so unchanged files don't get read again
+	postings map[string][]string      // the paths of the text files by the words they contain
+	words    []string                 // the words of the postings, sorted
+	grams    map[string][]int32       // the positions inside of the words of all words, that contain a part of 2 or 3 bytes, by that part
+	archives map[string]archiveRecord // the members of the archives by path, so unchanged archives don't get read again
+	fields   map[string]fieldRecord   // the metadata fields of the media files by path, so unchanged files don't get read again
+}
+
+/*
+Filesystem holds a ScopeCache for every scope of the config.
+
+While a Filesystem gets generated for the first time, its entries are added as they get found, so it can already be searched.
+Any access to the Scopes has to happen between RLock and RUnlock.
+SetupProperly and Updateable can be read and set at any time, as they're atomic.
+*/
 type Filesystem struct {
-	MainDirs      map[string]map[int][][]interface{}
-	SecondaryDirs map[string]map[int][][]interface{}
+	Scopes map[string]*ScopeCache
+
+	SetupProperly atomic.Bool
+	Updateable    atomic.Bool
+
+	mutex      sync.RWMutex
+	generating bool
+	ready      chan struct{}
+}
 
-	SetupProperly bool
-	Updateable    bool
+func init() {
+	entrieFilesystem.Store(newFilesystem())
 }
 
-// New returns a pointer to a Filesystem struct that has been filled up according to the config file
-func New(mainDirPaths []string, secondaryDirPaths []string) *Filesystem {
-	fs := Filesystem{
-		MainDirs:      make(map[string]map[int][][]interface{}),
-		SecondaryDirs: make(map[string]map[int][][]interface{}),
-		SetupProperly: false,
-		Updateable:    false,
+// newFilesystem returns a pointer to an empty Filesystem struct
+func newFilesystem() *Filesystem {
+	return &Filesystem{
+		Scopes: make(map[string]*ScopeCache),
+		ready:  make(chan struct{}),
 	}
+}
 
-	fs.Update(mainDirPaths, true)
-	fs.Update(secondaryDirPaths, false)
+// New returns a pointer to a Filesystem struct that has been filled up according to the scopes
+func New(scopes []*config.Scope) *Filesystem {
+	fs := newFilesystem()
+	fs.generate(scopes)
 
-	fs.SetupProperly = true
+	return fs
+}
 
-	return &fs
+// EntrieFilesystem returns the Filesystem that gets searched, it's safe to call while Generate replaces it
+func EntrieFilesystem() *Filesystem {
+	return entrieFilesystem.Load()
 }
 
-// Update gets and sets the folders set to either mainDirPaths or secondaryDirPaths
-func (fs *Filesystem) Update(dirPaths []string, isMainDirs bool) {
-	fs.Updateable = false
-	defer func() {
-		fs.Updateable = true
+/*
+Generate replaces the EntrieFilesystem with a new Filesystem, that gets filled up in the background.
+The scopes of a regular search get crawled first, so they're complete as early as possible.
+If the EntrieFilesystem is already being generated, it simply gets returned instead.
+
+Use Ready on the returned Filesystem to wait until it's done.
+*/
+func Generate(scopes []*config.Scope) *Filesystem {
+	generateMutex.Lock()
+	defer generateMutex.Unlock()
+
+	if current := entrieFilesystem.Load(); current.generating {
+		return current
+	}
+
+	fs := newFilesystem()
+	fs.generating = true
+	entrieFilesystem.Store(fs)
+
+	go func() {
+		fs.generate(scopes)
+
+		generateMutex.Lock()
+		defer generateMutex.Unlock()
+
+		fs.generating = false
 	}()
 
-	// if we aren't adding to the MainDirs add the excluded MainDirs directly to the queue
-	if !isMainDirs {
-		dirPaths = append(dirPaths, config.BWSConfig.ExcludeSubMainDirs...)
+	return fs
+}
+
+// generate fills up the fs with all scopes and closes the ready channel afterwards
+func (fs *Filesystem) generate(scopes []*config.Scope) {
+	ordered := []*config.Scope{}
+	for _, scope := range scopes {
+		if !scope.Extended {
+			ordered = append(ordered, scope)
+		}
+	}
+	for _, scope := range scopes {
+		if scope.Extended {
+			ordered = append(ordered, scope)
+		}
 	}
 
-	if len(dirPaths) < 1 {
-		return
+	names := []string{}
+	for _, scope := range ordered {
+		names = append(names, scope.Name)
 	}
 
-	// 10000000 is the channel size, because we just need a ridiculously large channel to store all the paths until we traversed them
-	var pathQueue = make(chan string, 10000000)
+	crawl := IndexProgress.start(PhaseIndexing, names)
+	defer IndexProgress.finish(crawl)
 
-	for _, dir := range dirPaths {
-		pathQueue <- dir
+	for _, scope := range ordered {
+		fs.Update(scope)
 	}
 
-	var wg sync.WaitGroup
+	fs.SetupProperly.Store(true)
+
+	close(fs.ready)
+}
+
+// Ready returns a channel that gets closed, once the fs has been fully generated
+func (fs *Filesystem) Ready() <-chan struct{} {
+	return fs.ready
+}
+
+// RLock locks the fs for reading, so it can be searched
+func (fs *Filesystem) RLock() {
+	fs.mutex.RLock()
+}
+
+// RUnlock undoes a single RLock call
+func (fs *Filesystem) RUnlock() {
+	fs.mutex.RUnlock()
+}
 
-	// 10000000 is the channel size, because we just need a ridiculously large channel to store all the results until we add them to the fs
-	resultsChan := make(chan *[]string, 10000000)
+// Outdated checks if the scope has been crawled completely and is due for its next update
+func (fs *Filesystem) Outdated(scope *config.Scope) bool {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
 
-	for range config.BWSConfig.CPUThreads {
-		wg.Add(1)
-		go fs.traverse(isMainDirs, pathQueue, resultsChan, &wg)
-		time.Sleep(5 * time.Millisecond)
+	scopeCache, ok := fs.Scopes[scope.Name]
+	if !ok || !scopeCache.Ready || scope.Refresh <= 0 {
+		return false
 	}
 
-	wg.Wait()
+	return time.Since(scopeCache.LastUpdate) >= scope.Refresh
+}
 
-	close(resultsChan)
-	close(pathQueue)
+// Update crawls the roots of the scope and replaces its entries in the fs
+func (fs *Filesystem) Update(scope *config.Scope) {
+	fs.Updateable.Store(false)
+	defer fs.Updateable.Store(true)
+
+	// if we're not part of a full generation of the cache, this is a crawl of its own
+	if id, ok := IndexProgress.startIfIdle(PhaseUpdating, []string{scope.Name}); ok {
+		defer IndexProgress.finish(id)
+	}
 
-	fs.add(resultsChan, isMainDirs)
+	IndexProgress.nextTier(scope.Name)
+	defer IndexProgress.endTier(scope.Name)
+
+	newCrawler(scope).run(scope.Roots, fs, max(config.BWSConfig.CPUThreads, 1))
 }
 
-// walkDir walks through the pathQueue and adds all new and valid entries into the resultsChan
-func (fs *Filesystem) traverse(isMainDirs bool, pathQueue chan string, resultsChan chan<- *[]string, wg *sync.WaitGroup) {
-	// when the queue is empty disolve the worker
-	defer wg.Done()
+// newEntry returns a pointer to an Entry, with the extension split from the name of files
+func newEntry(path string, name string, isFolder bool) *Entry {
+	baseName, extension := name, "Folder"
+	if !isFolder {
+		baseName, extension = SplitExtension(name)
+	}
 
-	// loop over the queue until it's empty
-	for {
-		currentDir := <-pathQueue
-		currentEntries, err := os.ReadDir(currentDir)
-		if err != nil {
-			// an error here simply means we didn't have the permissions to read a dir, so we ignore it
-			if len(pathQueue) < 1 {
-				break
-			}
+	return &Entry{
+		Path:      path,
+		Name:      strings.ToLower(baseName),
+		FullName:  strings.ToLower(name),
+		Extension: extension,
+		Encoded:   Encode(name),
+	}
+}
 
-			continue
-		}
+/*
+SplitExtension splits the name of a file into its base name and its lower case extension.
 
-		for _, entry := range currentEntries {
-			if entry.IsDir() {
-				entryPath := util.FormatEntry(filepath.Join(currentDir, entry.Name()), true)
-
-				// check if the current dir is an excluded name
-				if sslslices.Contains[string](config.BWSConfig.ExcludeDirsByName, util.FormatEntry(entry.Name(), true)) {
-					continue
-				}
-
-				// check if the dir is excluded
-				if sslslices.Contains[string](config.BWSConfig.ExcludeDirs, entryPath) {
-					continue
-				}
-
-				// check if we found a MainDirs folder while not MainDirs working with MainDirs
-				if !isMainDirs && sslslices.Contains[string](config.BWSConfig.MainDirs, entryPath) {
-					continue
-				}
-
-				// check if the dir is in the excluded main dirs
-				if isMainDirs && sslslices.Contains[string](config.BWSConfig.ExcludeSubMainDirs, entryPath) {
-					continue
-				}
-
-				resultsChan <- &[]string{entryPath, entry.Name(), "Folder"}
-				pathQueue <- entryPath
-			} else {
-				entryPath := util.FormatEntry(filepath.Join(currentDir, entry.Name()), false)
-				fileExtension := filepath.Ext(entry.Name())
-
-				if len(fileExtension) < 1 {
-					fileExtension = "File"
-				}
-				resultsChan <- &[]string{entryPath, entry.Name(), fileExtension}
-			}
+The CompoundExtensions of the config (e.g. ".tar.gz") are kept together, a leading "." marks a hidden file and not an extension.
+Names without an extension get "File" as theirs.
+*/
+func SplitExtension(name string) (string, string) {
+	for _, compound := range config.BWSConfig.CompoundExtensions {
+		if len(name) > len(compound) && strings.EqualFold(name[len(name)-len(compound):], compound) {
+			return name[:len(name)-len(compound)], compound
 		}
+	}
 
-		if len(pathQueue) < 1 {
-			return
-		}
+	index := strings.LastIndex(name, ".")
+	if index < 1 || index == len(name)-1 {
+		return name, "File"
 	}
+
+	return name[:index], strings.ToLower(name[index:])
 }
 
-// add adds the newEntries to the fs
-func (fs *Filesystem) add(resultsChan <-chan *[]string, isMainDirs bool) {
-	tempStorage := make(map[string]map[int][][]interface{})
+/*
+add adds the entries from the resultsChan to the scope in the fs and sums them up into the Folders above them, up to their root.
+
+If the scope hasn't been crawled before, the entries get added in batches right away, so the fs can be searched during the crawl.
+Otherwise they only replace the old entries at the end, so no search ever sees a partially updated scope.
+*/
+func (fs *Filesystem) add(resultsChan <-chan *Entry, scopeName string, roots []string) {
+	fs.mutex.Lock()
+	scopeCache, ok := fs.Scopes[scopeName]
+	if !ok {
+		scopeCache = &ScopeCache{Entries: make(map[string]map[int][]*Entry), FieldExtensions: make(map[string]bool), Folders: make(map[string]*FolderUsage)}
+		fs.Scopes[scopeName] = scopeCache
+	}
+	live := !scopeCache.Ready
+	fs.mutex.Unlock()
+
+	tempStorage := make(map[string]map[int][]*Entry)
+	fieldExtensions := make(map[string]bool)
+	folders := make(map[string]*FolderUsage)
+	brokenLinks := []string{}
+	batch := make([]*Entry, 0, addBatchSize)
+
+	// flush adds the batch to the live entries of the scope
+	flush := func() {
+		fs.mutex.Lock()
+		defer fs.mutex.Unlock()
+
+		for _, entry := range batch {
+			insert(scopeCache.Entries, entry)
+			addUsage(scopeCache.Folders, entry, roots)
+
+			if entry.Fields != nil {
+				scopeCache.FieldExtensions[entry.Extension] = true
+			}
+		}
 
-	for {
-		item, ok := <-resultsChan
+		batch = batch[:0]
+	}
 
-		// check if the channel is closed and empty
-		if !ok && len(resultsChan) < 1 {
-			break
+	for entry := range resultsChan {
+		if entry.Broken {
+			brokenLinks = append(brokenLinks, entry.Path)
 		}
 
-		itemPath := (*item)[0]
-		itemName := (*item)[1]
-		itemExtension := (*item)[2]
-
-		// trim file extensions from the name, if it has one
-		if itemExtension != "File" && itemExtension != "Folder" {
-			itemName = itemName[:len(itemName)-len(itemExtension)]
+		if entry.Fields != nil {
+			fieldExtensions[entry.Extension] = true
 		}
 
-		// check if the file type is already stored in the fs, if not add it in
-		if _, ok := tempStorage[itemExtension]; !ok {
-			tempStorage[itemExtension] = make(map[int][][]interface{})
+		if !live {
+			insert(tempStorage, entry)
+			addUsage(folders, entry, roots)
+			continue
 		}
 
-		// check if the file length is already stored for the file extension, if not add it in
-		if _, ok := tempStorage[itemExtension][len(itemName)]; !ok {
-			tempStorage[itemExtension][len(itemName)] = [][]interface{}{}
+		batch = append(batch, entry)
+		if len(batch) >= addBatchSize {
+			flush()
 		}
+	}
+
+	if live {
+		flush()
+	}
 
-		// add the file into the fs at its length with the format: [path, name, [encoded bytes]]
-		tempStorage[itemExtension][len(itemName)] = append(tempStorage[itemExtension][len(itemName)], []interface{}{itemPath, strings.ToLower(itemName), Encode(itemName)})
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if live {
+		tempStorage = scopeCache.Entries
+		folders = scopeCache.Folders
+	}
+
+	// the roots themselves are never sent as entries, so an empty root still needs its FolderUsage
+	for _, root := range roots {
+		folderUsage(folders, root)
+	}
+
+	// the workers deliver their entries in whatever order they got scheduled, so we sort them to always get the same fs
+	for _, lengthMaps := range tempStorage {
+		for _, entries := range lengthMaps {
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].Path < entries[j].Path
+			})
+		}
 	}
+	sort.Strings(brokenLinks)
+
+	scopeCache.Entries = tempStorage
+	scopeCache.FieldExtensions = fieldExtensions
+	scopeCache.Folders = folders
+	scopeCache.BrokenLinks = brokenLinks
+	scopeCache.Ready = true
+	scopeCache.LastUpdate = time.Now()
+}
 
-	if isMainDirs {
-		fs.MainDirs = tempStorage
-	} else {
-		fs.SecondaryDirs = tempStorage
+// insert adds a single entry into the storage
+func insert(storage map[string]map[int][]*Entry, entry *Entry) {
+	// check if the file type is already stored in the fs, if not add it in
+	if _, ok := storage[entry.Extension]; !ok {
+		storage[entry.Extension] = make(map[int][]*Entry)
 	}
+
+	// add the entry into the fs at the length of its name
+	storage[entry.Extension][len(entry.Name)] = append(storage[entry.Extension][len(entry.Name)], entry)
 }
@@ -0,0 +1,380 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	minWordLength  int = 2   // shorter words are too common to be worth a posting
+	maxWordLength  int = 64  // longer words are most likely encoded data and not something anyone searches for
+	snippetLength  int = 160 // the maximum amount of bytes of a snippet
+	binaryTestSize int = 512 // the amount of bytes at the start of a file, that may not contain a NUL byte
+	gramLength     int = 3   // the length in bytes of the parts of the words, that the grams index
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// contentRecord are the words of a text file, together with the size and modification time it had back then
+type contentRecord struct {
+	words   []string
+	size    int64
+	modTime time.Time
+}
+
+/*
+contentIndexer collects the words of all text files during a crawl.
+
+Files that haven't changed since the previous crawl keep their words without being read again.
+All other files wait for the rateLimiter, just like the sniffing of MIME types.
+*/
+type contentIndexer struct {
+	limiter  *rateLimiter
+	maxSize  int64
+	previous map[string]contentRecord
+
+	mutex   sync.Mutex
+	records map[string]contentRecord
+}
+
+// newContentIndexer returns a pointer to a contentIndexer, that reuses the previous records
+func newContentIndexer(previous map[string]contentRecord, limiter *rateLimiter, maxSize int64) *contentIndexer {
+	if previous == nil {
+		previous = make(map[string]contentRecord)
+	}
+
+	return &contentIndexer{
+		limiter:  limiter,
+		maxSize:  maxSize,
+		previous: previous,
+		records:  make(map[string]contentRecord),
+	}
+}
+
+// index records the words of the file at filePath, if it's a text file that isn't larger than the maxSize
+func (indexer *contentIndexer) index(filePath string, info os.FileInfo) {
+	if info.Size() < 1 || info.Size() > indexer.maxSize {
+		return
+	}
+
+	record, ok := indexer.previous[filePath]
+	if !ok || record.size != info.Size() || !record.modTime.Equal(info.ModTime()) {
+		indexer.limiter.wait()
+
+		text, ok := ReadText(filePath, indexer.maxSize)
+		if !ok {
+			return
+		}
+
+		record = contentRecord{words: tokenize(text), size: info.Size(), modTime: info.ModTime()}
+	}
+
+	indexer.mutex.Lock()
+	defer indexer.mutex.Unlock()
+
+	indexer.records[filePath] = record
+}
+
+/*
+ReadText reads the whole file at filePath, if it's a text file that isn't larger than the maxSize.
+
+A file counts as text, if it's valid UTF-8 and its start doesn't contain a NUL byte.
+*/
+func ReadText(filePath string, maxSize int64) ([]byte, bool) {
+	info, err := os.Stat(filePath)
+	if err != nil || info.Size() > maxSize {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil || int64(len(data)) > maxSize {
+		return nil, false
+	}
+
+	if bytes.IndexByte(data[:min(len(data), binaryTestSize)], 0) >= 0 || !utf8.Valid(data) {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// tokenize returns the unique lower case words of the text, a word being any run of letters, digits and "_"
+func tokenize(text []byte) []string {
+	found := make(map[string]bool)
+	words := []string{}
+
+	isWordChar := func(char rune) bool {
+		return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_'
+	}
+
+	for _, word := range bytes.FieldsFunc(text, func(char rune) bool { return !isWordChar(char) }) {
+		if len(word) < minWordLength || len(word) > maxWordLength {
+			continue
+		}
+
+		lowered := strings.ToLower(string(word))
+		if found[lowered] {
+			continue
+		}
+
+		found[lowered] = true
+		words = append(words, lowered)
+	}
+
+	sort.Strings(words)
+
+	return words
+}
+
+// contentRecords returns the content records of the last crawl of the scope
+func (fs *Filesystem) contentRecords(scopeName string) map[string]contentRecord {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		return scopeCache.contents
+	}
+
+	return nil
+}
+
+// setContentRecords stores the content records of a crawl in the scope of the fs and builds the postings and grams of their words
+func (fs *Filesystem) setContentRecords(scopeName string, records map[string]contentRecord) {
+	postings := make(map[string][]string)
+
+	for filePath, record := range records {
+		for _, word := range record.words {
+			postings[word] = append(postings[word], filePath)
+		}
+	}
+
+	words := make([]string, 0, len(postings))
+	for word := range postings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	grams := buildGrams(words)
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		scopeCache.contents = records
+		scopeCache.postings = postings
+		scopeCache.words = words
+		scopeCache.grams = grams
+	}
+}
+
+/*
+buildGrams returns the positions of the words by every part of minWordLength up to gramLength bytes, that they contain.
+
+Every list is sorted, so the lists of multiple grams can be intersected in a single pass.
+*/
+func buildGrams(words []string) map[string][]int32 {
+	grams := make(map[string][]int32)
+
+	for position, word := range words {
+		for length := minWordLength; length <= gramLength; length++ {
+			for start := 0; start+length <= len(word); start++ {
+				gram := word[start : start+length]
+
+				// a gram that appears multiple times inside of a word, only gets its position once
+				if list := grams[gram]; len(list) > 0 && list[len(list)-1] == int32(position) {
+					continue
+				}
+
+				grams[gram] = append(grams[gram], int32(position))
+			}
+		}
+	}
+
+	return grams
+}
+
+/*
+ContentMatches returns the paths of all text files in the scope, that contain every one of the terms.
+
+A term is split into words like the files are, and each word may be part of a longer word inside of the file (e.g. "port" matches "listen_port").
+Words shorter than the minWordLength aren't indexed, so a term without any longer word matches nothing.
+If the content of the scope isn't indexed, it returns false.
+The fs has to be locked for reading, while this runs.
+*/
+func (scopeCache *ScopeCache) ContentMatches(terms []string) (map[string]bool, bool) {
+	if scopeCache.contents == nil {
+		return nil, false
+	}
+
+	// matches stays nil until the first word narrowed it down
+	var matches map[string]bool
+
+	for _, term := range terms {
+		words := tokenize([]byte(term))
+		if len(words) < 1 {
+			return map[string]bool{}, true
+		}
+
+		for _, word := range words {
+			found := make(map[string]bool)
+
+			for _, position := range scopeCache.wordsContaining(word) {
+				for _, filePath := range scopeCache.postings[scopeCache.words[position]] {
+					if matches == nil || matches[filePath] {
+						found[filePath] = true
+					}
+				}
+			}
+
+			matches = found
+			if len(matches) < 1 {
+				return matches, true
+			}
+		}
+	}
+
+	if matches == nil {
+		matches = map[string]bool{}
+	}
+
+	return matches, true
+}
+
+/*
+wordsContaining returns the positions of all indexed words, that contain the word.
+
+A word up to the gramLength is a gram itself. For longer words, only the words that contain all of their grams get checked.
+*/
+func (scopeCache *ScopeCache) wordsContaining(word string) []int32 {
+	if len(word) <= gramLength {
+		return scopeCache.grams[word]
+	}
+
+	var candidates []int32
+	for start := 0; start+gramLength <= len(word); start++ {
+		positions := scopeCache.grams[word[start:start+gramLength]]
+		if candidates == nil {
+			candidates = positions
+		} else {
+			candidates = intersectSorted(candidates, positions)
+		}
+
+		if len(candidates) < 1 {
+			return nil
+		}
+	}
+
+	containing := []int32{}
+	for _, position := range candidates {
+		if strings.Contains(scopeCache.words[position], word) {
+			containing = append(containing, position)
+		}
+	}
+
+	return containing
+}
+
+// intersectSorted returns the values, that are in both of the sorted lists
+func intersectSorted(first []int32, second []int32) []int32 {
+	both := []int32{}
+
+	for len(first) > 0 && len(second) > 0 {
+		switch {
+		case first[0] < second[0]:
+			first = first[1:]
+		case first[0] > second[0]:
+			second = second[1:]
+		default:
+			both = append(both, first[0])
+			first, second = first[1:], second[1:]
+		}
+	}
+
+	return both
+}
+
+/*
+Snippet returns the first line of the file at filePath, that contains any of the terms (case insensitive), shortened around the term.
+
+The file gets read line by line and only until the first match. If no line contains a term, it returns an empty string.
+*/
+func Snippet(filePath string, terms []string, maxSize int64) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err != nil || info.Size() > maxSize {
+		return ""
+	}
+
+	loweredTerms := make([]string, 0, len(terms))
+	for _, term := range terms {
+		loweredTerms = append(loweredTerms, strings.ToLower(term))
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), int(max(maxSize, int64(bufio.MaxScanTokenSize))))
+
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		lowered := strings.ToLower(line)
+
+		for _, term := range loweredTerms {
+			index := strings.Index(lowered, term)
+			if index < 0 {
+				continue
+			}
+
+			// lower casing can change the length of some characters, in which case the index doesn't fit the line
+			if len(lowered) != len(line) {
+				index = 0
+			}
+
+			return shorten(line, index)
+		}
+	}
+
+	return ""
+}
+
+// shorten cuts the line down to the snippetLength around the index, without splitting any characters
+func shorten(line string, index int) string {
+	line = strings.TrimRight(line, " \t\r")
+	if len(line) <= snippetLength {
+		return line
+	}
+
+	start := max(index-snippetLength/4, 0)
+	end := min(start+snippetLength, len(line))
+
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end--
+	}
+
+	snippet := line[start:end]
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(line) {
+		snippet += "…"
+	}
+
+	return snippet
+}
@@ -0,0 +1,503 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skillptm/ssl/pkg/sslslices"
+
+	"github.com/skillptm/bws/internal/config"
+	"github.com/skillptm/bws/internal/ignore"
+	"github.com/skillptm/bws/internal/util"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	pathQueueSize   int = 1024 // folders that can wait in the shared queue, before a worker keeps them on its own stack
+	resultsChanSize int = 4096 // entries that can wait to be added to the fs, before the workers have to wait for add
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// crawlDir is a folder that still has to be read, together with the root dir it was found in and the rules of the ignore files above it
+type crawlDir struct {
+	path    string
+	root    string
+	ignores *ignore.Stack
+}
+
+/*
+crawler holds the shared state of a single traversal over a set of dirs.
+
+Every folder that still has to be read is counted in pending, from the moment it gets queued until a worker has finished reading it.
+That way the crawl only ends once no folder is queued or being read anymore, no matter how the workers got scheduled.
+*/
+type crawler struct {
+	scope       *config.Scope
+	roots       map[string]bool
+	symlinks    string
+	pathQueue   chan crawlDir
+	resultsChan chan *Entry
+	pending     sync.WaitGroup
+
+	visitedMutex sync.Mutex
+	visited      map[FileID]bool
+
+	mounts      map[string]string
+	rootDevices map[string]uint64
+	report      *crawlReport
+	sniffer     *sniffer
+	indexer     *contentIndexer
+	archiver    *archiver
+	fieldReader *fieldReader
+}
+
+// newCrawler returns a pointer to a crawler with bounded queues for the scope
+func newCrawler(scope *config.Scope) *crawler {
+	return &crawler{
+		scope:       scope,
+		roots:       config.BWSConfig.Roots(),
+		symlinks:    config.BWSConfig.Symlinks,
+		pathQueue:   make(chan crawlDir, pathQueueSize),
+		resultsChan: make(chan *Entry, resultsChanSize),
+		visited:     make(map[FileID]bool),
+		mounts:      readMounts(),
+		rootDevices: make(map[string]uint64),
+		report:      newCrawlReport(),
+	}
+}
+
+// run traverses all dirPaths with the provided amount of workers and adds the results to the fs once it's done
+func (c *crawler) run(dirPaths []string, fs *Filesystem, workers int) {
+	// sniffing and the content index share the limiter, so together they don't read more files per second than the SniffRate
+	limiter := newRateLimiter(config.BWSConfig.SniffRate)
+
+	if config.BWSConfig.SniffMIME {
+		c.sniffer = newSniffer(fs.mimeRecords(c.scope.Name), limiter)
+	}
+
+	if config.BWSConfig.IndexContent {
+		c.indexer = newContentIndexer(fs.contentRecords(c.scope.Name), limiter, config.BWSConfig.ContentMaxSize)
+	}
+
+	if config.BWSConfig.IndexArchives {
+		c.archiver = newArchiver(fs.archiveRecords(c.scope.Name), limiter, config.BWSConfig.ArchiveMaxSize, config.BWSConfig.ArchiveMaxMembers)
+	}
+
+	if extractors := activeExtractors(config.BWSConfig.ExtractMetadata); len(extractors) > 0 {
+		c.fieldReader = newFieldReader(fs.fieldRecords(c.scope.Name), limiter, extractors, config.BWSConfig.ExtractorConcurrency, config.BWSConfig.ExtractorTimeout)
+	}
+
+	c.pending.Add(len(dirPaths))
+	IndexProgress.tierQueued.Add(int64(len(dirPaths)))
+
+	// a link could lead back to a root, so the roots count as visited and their device is the one we stay on
+	for _, dir := range dirPaths {
+		if info, err := os.Stat(dir); err == nil {
+			c.firstVisit(dir, info)
+
+			if device, ok := getDevice(dir, info); ok {
+				c.rootDevices[dir] = device
+			}
+		}
+	}
+
+	// the queue might be smaller than the amount of dirPaths, so we feed them in while the workers already run
+	go func() {
+		for _, dir := range dirPaths {
+			c.pathQueue <- crawlDir{path: dir, root: dir}
+		}
+	}()
+
+	// once every folder has been read, there is nothing left that could be queued
+	go func() {
+		c.pending.Wait()
+		close(c.pathQueue)
+	}()
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+		go c.traverse(&wg)
+	}
+
+	// once every worker is done, there are no more results that could be sent
+	go func() {
+		wg.Wait()
+		close(c.resultsChan)
+	}()
+
+	// add consumes the results while the workers are running, so a slow add slows down the workers instead of filling up the memory
+	fs.add(c.resultsChan, c.scope.Name, c.scope.Roots)
+	fs.setReport(c.scope.Name, c.report)
+
+	if c.sniffer != nil {
+		fs.setMIMERecords(c.scope.Name, c.sniffer.records)
+	}
+
+	if c.indexer != nil {
+		fs.setContentRecords(c.scope.Name, c.indexer.records)
+	}
+
+	if c.archiver != nil {
+		fs.setArchiveRecords(c.scope.Name, c.archiver.records)
+	}
+
+	if c.fieldReader != nil {
+		fs.setFieldRecords(c.scope.Name, c.fieldReader.records)
+	}
+}
+
+// traverse reads the folders from the pathQueue and sends all new and valid entries into the resultsChan
+func (c *crawler) traverse(wg *sync.WaitGroup) {
+	// when the queue is closed disolve the worker
+	defer wg.Done()
+
+	for dir := range c.pathQueue {
+		stack := []crawlDir{dir}
+
+		// folders that didn't fit into the pathQueue end up on the stack, so we never block on the queue we consume from ourselves
+		for len(stack) > 0 {
+			currentDir := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for _, subDir := range c.readDir(currentDir) {
+				c.pending.Add(1)
+				IndexProgress.tierQueued.Add(1)
+
+				select {
+				case c.pathQueue <- subDir:
+				default:
+					stack = append(stack, subDir)
+				}
+			}
+
+			c.pending.Done()
+		}
+	}
+}
+
+// readDir sends all valid entries of currentDir into the resultsChan and returns the sub folders that still have to be read
+func (c *crawler) readDir(current crawlDir) []crawlDir {
+	subDirs := []crawlDir{}
+	currentDir := current.path
+
+	IndexProgress.visited(current.root)
+
+	currentEntries, err := readDirWithTimeout(currentDir, config.BWSConfig.ReadTimeout)
+	if err != nil {
+		// we can't read the dir (most likely we're missing the permissions), so its whole subtree is missing from the cache
+		c.report.addError(currentDir, err)
+		return subDirs
+	}
+
+	ignores := c.loadIgnoreFiles(current, currentEntries)
+
+	for _, entry := range currentEntries {
+		isLink := entry.Type()&os.ModeSymlink != 0
+		isDir := entry.IsDir()
+		broken := false
+
+		// for links we need to know, what they point to
+		var info os.FileInfo
+		if isLink {
+			info, err = os.Stat(filepath.Join(currentDir, entry.Name()))
+			if err != nil {
+				broken = true
+			} else {
+				isDir = info.IsDir()
+			}
+		}
+
+		if isDir {
+			entryPath := util.FormatEntry(filepath.Join(currentDir, entry.Name()), true)
+
+			if reason := c.dirExcluded(current.root, ignores, entry, entryPath); len(reason) > 0 {
+				c.report.addExcluded(entryPath, reason)
+				continue
+			}
+
+			newDir := newEntry(entryPath, entry.Name(), true)
+			newDir.IsLink = isLink
+			c.resultsChan <- newDir
+			IndexProgress.entriesIndexed.Add(1)
+
+			if reason := c.descendExcluded(current.root, entry, entryPath, isLink, info); len(reason) > 0 {
+				c.report.addExcluded(entryPath, reason)
+				continue
+			}
+
+			subDirs = append(subDirs, crawlDir{path: entryPath, root: current.root, ignores: ignores})
+		} else {
+			entryPath := util.FormatEntry(filepath.Join(currentDir, entry.Name()), false)
+
+			// check if the file is excluded by a pattern
+			if reason := c.excludedByPattern(current.root, ignores, entryPath, false); len(reason) > 0 {
+				c.report.addExcluded(entryPath, reason)
+				continue
+			}
+
+			newFile := newEntry(entryPath, entry.Name(), false)
+			newFile.IsLink = isLink
+			newFile.Broken = broken
+
+			// links already know the info of their target, the size of broken links stays 0
+			// the info of every file is needed for its size and costs a stat call on unix, windows already read it with the folder
+			members := []*Entry{}
+			if !broken {
+				if info == nil {
+					info, err = entry.Info()
+				}
+
+				if err == nil {
+					newFile.Size = info.Size()
+					newFile.ModTime = info.ModTime()
+				}
+
+				if err == nil && c.sniffer != nil {
+					newFile.MIME = c.sniffer.sniff(entryPath, info)
+				}
+
+				if err == nil && c.indexer != nil {
+					c.indexer.index(entryPath, info)
+				}
+
+				if err == nil && c.archiver != nil {
+					members = c.archiver.members(entryPath, newFile.Extension, info)
+				}
+
+				if err == nil && c.fieldReader != nil {
+					newFile.Fields = c.fieldReader.read(newFile, info)
+				}
+			}
+
+			c.resultsChan <- newFile
+			IndexProgress.entriesIndexed.Add(1)
+
+			for _, member := range members {
+				c.resultsChan <- member
+				IndexProgress.entriesIndexed.Add(1)
+			}
+		}
+	}
+
+	return subDirs
+}
+
+// dirExcluded returns the reason, why the dir is left out of the cache entirely, or an empty string if it isn't
+func (c *crawler) dirExcluded(root string, ignores *ignore.Stack, entry os.DirEntry, entryPath string) string {
+	// check if the dir is excluded by a pattern
+	if reason := c.excludedByPattern(root, ignores, entryPath, true); len(reason) > 0 {
+		return reason
+	}
+
+	// check if the current dir is an excluded name
+	if sslslices.Contains[string](config.BWSConfig.ExcludeDirsByName, util.FormatEntry(entry.Name(), true)) {
+		return ExcludedByName
+	}
+
+	// check if the dir is excluded
+	if sslslices.Contains[string](config.BWSConfig.ExcludeDirs, entryPath) {
+		return ExcludedDir
+	}
+
+	// check if the dir is excluded from the scope
+	if sslslices.Contains[string](c.scope.Excludes, entryPath) {
+		return ExcludedFromScope
+	}
+
+	// check if the dir is the root of a scope, if so it gets crawled from there
+	if c.roots[entryPath] {
+		return ExcludedRoot
+	}
+
+	// check if the dir is the mount point of an excluded filesystem type
+	if c.excludedMount(entryPath) {
+		return ExcludedFSType
+	}
+
+	return ""
+}
+
+/*
+descendExcluded returns the reason, why the crawl doesn't continue into the dir, or an empty string if it does.
+
+As soon as links get followed, the same folder could be reached by multiple paths, so every folder is only crawled the first time we see it.
+Where identifying a folder is expensive (see identifyLinksOnly) only links get identified, which still stops every loop.
+*/
+func (c *crawler) descendExcluded(root string, entry os.DirEntry, entryPath string, isLink bool, info os.FileInfo) string {
+	following := c.symlinks == config.SymlinksWithinRoots || c.symlinks == config.SymlinksAlways
+
+	if isLink && (!following || (c.symlinks == config.SymlinksWithinRoots && !c.withinRoots(entryPath))) {
+		return ExcludedSymlink
+	}
+
+	// we only need the info of a regular dir, if we have to identify or locate it
+	if !isLink && (following || config.BWSConfig.OneFilesystem) {
+		var err error
+		info, err = entry.Info()
+		if err != nil {
+			c.report.addError(entryPath, err)
+			return ExcludedUnreadable
+		}
+	}
+
+	if following && (isLink || !identifyLinksOnly) && !c.firstVisit(entryPath, info) {
+		return ExcludedVisited
+	}
+
+	if !c.onRootDevice(root, entryPath, info) {
+		return ExcludedFilesystem
+	}
+
+	return ""
+}
+
+// withinRoots checks if the target of the link at entryPath is inside one of the roots of the scope
+func (c *crawler) withinRoots(entryPath string) bool {
+	target, err := filepath.EvalSymlinks(entryPath)
+	if err != nil {
+		return false
+	}
+	target = util.FormatEntry(target, true)
+
+	for _, root := range c.scope.Roots {
+		if strings.HasPrefix(target, root) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstVisit marks the dir as visited and reports if that's the first time, if the dir can't be identified it always counts as the first time
+func (c *crawler) firstVisit(dirPath string, info os.FileInfo) bool {
+	id, ok := GetFileID(dirPath, info)
+	if !ok {
+		return true
+	}
+
+	c.visitedMutex.Lock()
+	defer c.visitedMutex.Unlock()
+
+	if c.visited[id] {
+		return false
+	}
+
+	c.visited[id] = true
+
+	return true
+}
+
+// excludedMount checks if the dir is a mount point, whose filesystem type is excluded by the config
+func (c *crawler) excludedMount(entryPath string) bool {
+	fsType, ok := c.mounts[entryPath]
+	if !ok {
+		return false
+	}
+
+	for _, pattern := range config.BWSConfig.ExcludeFSTypes {
+		if matched, _ := path.Match(pattern, fsType); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// onRootDevice checks if the dir is on the same device as the root it was found in, as long as the config wants us to stay on one filesystem
+func (c *crawler) onRootDevice(root string, entryPath string, info os.FileInfo) bool {
+	if !config.BWSConfig.OneFilesystem {
+		return true
+	}
+
+	rootDevice, ok := c.rootDevices[root]
+	if !ok {
+		return true
+	}
+
+	device, ok := getDevice(entryPath, info)
+
+	return !ok || device == rootDevice
+}
+
+// loadIgnoreFiles adds the rules of the ignore files inside the current dir to the ones from above it, if the config asks for it
+func (c *crawler) loadIgnoreFiles(current crawlDir, currentEntries []os.DirEntry) *ignore.Stack {
+	if !config.BWSConfig.UseIgnoreFiles {
+		return current.ignores
+	}
+
+	// we only read the ignore files that we know exist, so most folders don't cost us any extra reads
+	present := []string{}
+	for _, fileName := range config.BWSConfig.IgnoreFiles {
+		for _, entry := range currentEntries {
+			if !entry.IsDir() && entry.Name() == fileName {
+				present = append(present, fileName)
+				break
+			}
+		}
+	}
+
+	if len(present) < 1 {
+		return current.ignores
+	}
+
+	return current.ignores.Load(current.path, present)
+}
+
+// excludedByPattern returns, if the entry gets excluded by the ExcludeRules (relative to the root it was found in) or the ignore files above it
+func (c *crawler) excludedByPattern(root string, ignores *ignore.Stack, entryPath string, isDir bool) string {
+	if len(config.BWSConfig.ExcludeRules) > 0 && config.BWSConfig.ExcludeRules.Excluded(strings.TrimSuffix(strings.TrimPrefix(entryPath, root), "/"), isDir) {
+		return ExcludedByPattern
+	}
+
+	if ignores.Excluded(entryPath, isDir) {
+		return ExcludedByIgnore
+	}
+
+	return ""
+}
+
+/*
+readDirWithTimeout reads the dir like os.ReadDir, but gives up after the timeout, if it's above 0.
+
+A dead network mount can block a read forever, in which case the reading goroutine is left behind, so it can't stall the whole crawl.
+*/
+func readDirWithTimeout(dir string, timeout time.Duration) ([]os.DirEntry, error) {
+	if timeout <= 0 {
+		return os.ReadDir(dir)
+	}
+
+	type readResult struct {
+		entries []os.DirEntry
+		err     error
+	}
+
+	// the channel is buffered, so the goroutine can always finish, even if nobody waits for it anymore
+	resultChan := make(chan readResult, 1)
+
+	go func() {
+		entries, err := os.ReadDir(dir)
+		resultChan <- readResult{entries: entries, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-resultChan:
+		return result.entries, result.err
+	case <-timer.C:
+		return nil, fmt.Errorf("reading %s took longer than %s; %w", dir, timeout, errReadTimeout)
+	}
+}
@@ -0,0 +1,283 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skillptm/bws/internal/metadata"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	MetadataExtractor string = "metadata" // the name of the built-in Extractor for media files and documents
+)
+
+// reservedFields are the keys of the terms, that aren't fields, so no Extractor can use them
+var reservedFields = map[string]bool{"kind": true, "mime": true, "content": true, "foldersize": true}
+
+var (
+	extractorsMutex sync.RWMutex
+	extractors      = map[string]Extractor{}
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Extractor reads key/value fields from files during the cache generation.
+
+The fields get stored with the entry and can be searched with "key:value" terms inside of the searchString.
+Extract gets called for every new or changed file the Handles match, with a context that gets cancelled after the ExtractorTimeout.
+*/
+type Extractor interface {
+	Fields() []string
+	Handles() Handles
+	Extract(ctx context.Context, filePath string) (map[string]string, error)
+}
+
+/*
+Handles describes, which files an Extractor reads. A file is handled, if any of them match.
+
+Extensions are matched case insensitive with a leading "." (e.g. ".proj"). MIMEs may be whole types ("image/png"),
+only main types ("image") or globs ("application/x-*"), they use the sniffed MIME type if SniffMIME is turned on and the one guessed from the extension otherwise.
+*/
+type Handles struct {
+	Extensions []string
+	MIMEs      []string
+	Predicate  func(filePath string, info fs.FileInfo) bool
+}
+
+// metadataExtractor is the built-in Extractor, that wraps the readers of the metadata package
+type metadataExtractor struct{}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// RegisterExtractor adds the extractor under the name or replaces an existing one with that name
+func RegisterExtractor(name string, extractor Extractor) error {
+	if len(name) < 1 || name == MetadataExtractor {
+		return fmt.Errorf("'%s' can't be used as the name of an extractor", name)
+	}
+
+	if extractor == nil {
+		return fmt.Errorf("the extractor %s is nil", name)
+	}
+
+	fields, ok := extractorFields(extractor)
+	if !ok {
+		return fmt.Errorf("the extractor %s panicked while returning its fields", name)
+	}
+
+	for _, field := range fields {
+		if len(field) < 1 || field != strings.ToLower(field) || strings.ContainsAny(field, ": \"") || reservedFields[field] {
+			return fmt.Errorf("'%s' can't be used as the name of a field", field)
+		}
+	}
+
+	extractorsMutex.Lock()
+	defer extractorsMutex.Unlock()
+
+	extractors[name] = extractor
+
+	return nil
+}
+
+// RemoveExtractor removes the extractor with the name, it returns false if there is none
+func RemoveExtractor(name string) bool {
+	extractorsMutex.Lock()
+	defer extractorsMutex.Unlock()
+
+	_, ok := extractors[name]
+	delete(extractors, name)
+
+	return ok
+}
+
+// activeExtractors returns the registered extractors sorted by their name, with the built-in one first if withMetadata is set
+func activeExtractors(withMetadata bool) []Extractor {
+	extractorsMutex.RLock()
+	defer extractorsMutex.RUnlock()
+
+	names := []string{}
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	active := []Extractor{}
+	if withMetadata {
+		active = append(active, metadataExtractor{})
+	}
+
+	for _, name := range names {
+		active = append(active, extractors[name])
+	}
+
+	return active
+}
+
+// IsField checks if the key is a field of the built-in or any registered Extractor
+func IsField(key string) bool {
+	return sortedContains(FieldNames(), key)
+}
+
+// FieldNames returns the fields of the built-in and all registered extractors sorted alphabetically
+func FieldNames() []string {
+	found := map[string]bool{}
+	for _, field := range metadata.Fields {
+		found[field] = true
+	}
+
+	extractorsMutex.RLock()
+	for _, extractor := range extractors {
+		fields, _ := extractorFields(extractor)
+		for _, field := range fields {
+			found[field] = true
+		}
+	}
+	extractorsMutex.RUnlock()
+
+	names := []string{}
+	for field := range found {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// sortedContains checks if the sorted values contain the value
+func sortedContains(values []string, value string) bool {
+	index := sort.SearchStrings(values, value)
+	return index < len(values) && values[index] == value
+}
+
+// handles checks if the handles match the entry
+func (handles Handles) handles(entry *Entry, info fs.FileInfo) bool {
+	for _, extension := range handles.Extensions {
+		if strings.EqualFold(extension, entry.Extension) {
+			return true
+		}
+	}
+
+	if len(handles.MIMEs) > 0 {
+		mimeType := entry.MIMEType()
+
+		for _, pattern := range handles.MIMEs {
+			pattern = strings.ToLower(pattern)
+			if !strings.Contains(pattern, "/") {
+				pattern += "/*"
+			}
+
+			if matched, err := path.Match(pattern, mimeType); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return handles.Predicate != nil && predicateMatches(handles.Predicate, entry.Path, info)
+}
+
+// predicateMatches runs the predicate on the file, a predicate that panics doesn't match
+func predicateMatches(predicate func(filePath string, info fs.FileInfo) bool, filePath string, info fs.FileInfo) (matches bool) {
+	defer func() {
+		if recover() != nil {
+			matches = false
+		}
+	}()
+
+	return predicate(filePath, info)
+}
+
+// extractorFields returns the fields of the extractor, an extractor that panics has none and ok is false
+func extractorFields(extractor Extractor) (fields []string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			fields, ok = nil, false
+		}
+	}()
+
+	return extractor.Fields(), true
+}
+
+// extractorHandles returns the Handles of the extractor, an extractor that panics handles no files
+func extractorHandles(extractor Extractor) (handles Handles) {
+	defer func() {
+		if recover() != nil {
+			handles = Handles{}
+		}
+	}()
+
+	return extractor.Handles()
+}
+
+/*
+extract runs the extractor on the file at filePath, if it takes longer than the timeout its fields are left out.
+
+The extractor takes one of the slots while it runs and only gives it back once it returns, even if that's long after the timeout.
+If no slot gets free within the timeout, the extractor isn't run at all.
+An extractor that panics is treated like one that failed, so a single broken file or extractor can't take down the whole process.
+*/
+func extract(extractor Extractor, filePath string, timeout time.Duration, slots chan struct{}) map[string]string {
+	ctx := context.Background()
+	cancel := func() {}
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil
+	}
+
+	// the extractor might not respect the context, so we don't wait for it longer than the timeout either way
+	resultChan := make(chan map[string]string, 1)
+	go func() {
+		defer func() {
+			<-slots
+		}()
+		defer func() {
+			if recover() != nil {
+				resultChan <- nil
+			}
+		}()
+
+		fields, err := extractor.Extract(ctx, filePath)
+		if err != nil {
+			fields = nil
+		}
+		resultChan <- fields
+	}()
+
+	select {
+	case fields := <-resultChan:
+		return fields
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// Fields returns the fields of the metadata package
+func (metadataExtractor) Fields() []string {
+	return metadata.Fields
+}
+
+// Handles returns the extensions, the metadata package has a reader for
+func (metadataExtractor) Handles() Handles {
+	return Handles{Extensions: metadata.Extensions()}
+}
+
+// Extract reads the fields with the metadata package, the readers are fast enough to not need the ctx
+func (metadataExtractor) Extract(_ context.Context, filePath string) (map[string]string, error) {
+	return metadata.Read(filePath)
+}
@@ -0,0 +1,132 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// fieldRecord are the fields the extractors read from a file, together with the size and modification time it had back then
+type fieldRecord struct {
+	fields  map[string]string
+	size    int64
+	modTime time.Time
+}
+
+/*
+fieldReader runs the extractors on the files during a crawl.
+
+Files that haven't changed since the previous crawl keep their fields without being read again.
+All other files wait for the rateLimiter, just like the sniffing of MIME types, and at most the ExtractorConcurrency of extractors run at once.
+An extractor that hangs past the timeout keeps its slot until it returns, so hung extractors can't pile up.
+*/
+type fieldReader struct {
+	limiter    *rateLimiter
+	extractors []Extractor
+	handles    []Handles  // the Handles of the extractors at the same index, they only get asked once per crawl
+	fields     [][]string // the Fields of the extractors at the same index, they only get asked once per crawl
+	slots      chan struct{}
+	timeout    time.Duration
+	previous   map[string]fieldRecord
+
+	mutex   sync.Mutex
+	records map[string]fieldRecord
+}
+
+// newFieldReader returns a pointer to a fieldReader for the extractors, that reuses the previous records
+func newFieldReader(previous map[string]fieldRecord, limiter *rateLimiter, extractors []Extractor, concurrency int, timeout time.Duration) *fieldReader {
+	if previous == nil {
+		previous = make(map[string]fieldRecord)
+	}
+
+	handles := make([]Handles, 0, len(extractors))
+	fields := make([][]string, 0, len(extractors))
+	for _, extractor := range extractors {
+		handles = append(handles, extractorHandles(extractor))
+		declared, _ := extractorFields(extractor)
+		fields = append(fields, declared)
+	}
+
+	return &fieldReader{
+		limiter:    limiter,
+		extractors: extractors,
+		handles:    handles,
+		fields:     fields,
+		slots:      make(chan struct{}, max(concurrency, 1)),
+		timeout:    timeout,
+		previous:   previous,
+		records:    make(map[string]fieldRecord),
+	}
+}
+
+// read returns the fields of all extractors, that handle the entry, or nil if none does or there aren't any fields
+func (reader *fieldReader) read(entry *Entry, info os.FileInfo) map[string]string {
+	handling := []int{}
+	for index := range reader.extractors {
+		if reader.handles[index].handles(entry, info) {
+			handling = append(handling, index)
+		}
+	}
+
+	if len(handling) < 1 {
+		return nil
+	}
+
+	record, ok := reader.previous[entry.Path]
+	if !ok || record.size != info.Size() || !record.modTime.Equal(info.ModTime()) {
+		reader.limiter.wait()
+
+		fields := map[string]string{}
+
+		// a file we can't read is recorded without fields, so we don't try again until it changes
+		for _, index := range handling {
+			for key, value := range extract(reader.extractors[index], entry.Path, reader.timeout, reader.slots) {
+				// an extractor may only set the fields it declared
+				if len(value) > 0 && slices.Contains(reader.fields[index], key) {
+					fields[key] = value
+				}
+			}
+		}
+
+		if len(fields) < 1 {
+			fields = nil
+		}
+
+		record = fieldRecord{fields: fields, size: info.Size(), modTime: info.ModTime()}
+	}
+
+	reader.mutex.Lock()
+	defer reader.mutex.Unlock()
+
+	reader.records[entry.Path] = record
+
+	return record.fields
+}
+
+// fieldRecords returns the field records of the last crawl of the scope
+func (fs *Filesystem) fieldRecords(scopeName string) map[string]fieldRecord {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		return scopeCache.fields
+	}
+
+	return nil
+}
+
+// setFieldRecords stores the field records of a crawl in the scope of the fs, so the next crawl can reuse them
+func (fs *Filesystem) setFieldRecords(scopeName string, records map[string]fieldRecord) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		scopeCache.fields = records
+	}
+}
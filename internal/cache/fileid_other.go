@@ -0,0 +1,41 @@
+//go:build !unix && !windows
+
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	identifyLinksOnly bool = false // identify every folder, as resolving the links of a path doesn't open it
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// FileID identifies a file or folder independent of the path it was reached by (e.g. a link or another hard link)
+type FileID struct {
+	path string
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// GetFileID returns the path with all links resolved, as there is no portable device and inode on this platform
+func GetFileID(path string, _ os.FileInfo) (FileID, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return FileID{}, false
+	}
+
+	return FileID{path: resolved}, true
+}
+
+// getDevice can't tell the device on this platform, so every folder counts as being on the same one
+func getDevice(_ string, _ os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+	"syscall"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	identifyLinksOnly bool = false // identify every folder, as the info of every folder already carries its device and inode
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// FileID identifies a file or folder independent of the path it was reached by (e.g. a link or another hard link)
+type FileID struct {
+	device uint64
+	inode  uint64
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// GetFileID returns the device and inode of the info, the path isn't needed on unix
+func GetFileID(_ string, info os.FileInfo) (FileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, false
+	}
+
+	return FileID{device: uint64(stat.Dev), inode: uint64(stat.Ino)}, true
+}
+
+// getDevice returns the device the info belongs to
+func getDevice(path string, info os.FileInfo) (uint64, bool) {
+	id, ok := GetFileID(path, info)
+	return id.device, ok
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+	"syscall"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	identifyLinksOnly bool = true // only identify links, as opening a handle for every folder is slow on windows and only links can make a folder reachable twice
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// FileID identifies a file or folder independent of the path it was reached by (e.g. a link or another hard link)
+type FileID struct {
+	device uint64
+	inode  uint64
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// GetFileID returns the volume serial number and file index of the path, as the info on windows doesn't carry them
+func GetFileID(path string, _ os.FileInfo) (FileID, bool) {
+	pathPointer, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return FileID{}, false
+	}
+
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open a handle to a folder
+	handle, err := syscall.CreateFile(pathPointer, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return FileID{}, false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var fileInfo syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(handle, &fileInfo); err != nil {
+		return FileID{}, false
+	}
+
+	return FileID{
+		device: uint64(fileInfo.VolumeSerialNumber),
+		inode:  uint64(fileInfo.FileIndexHigh)<<32 | uint64(fileInfo.FileIndexLow),
+	}, true
+}
+
+// getDevice returns the volume serial number of the path
+func getDevice(path string, info os.FileInfo) (uint64, bool) {
+	id, ok := GetFileID(path, info)
+	return id.device, ok
+}
@@ -0,0 +1,81 @@
+//go:build linux
+
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/skillptm/bws/internal/util"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	mountInfoPath string = "/proc/self/mountinfo"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+readMounts returns the filesystem type of every mount point (with a trailing "/") from /proc/self/mountinfo.
+
+A line of the mountinfo looks like this, where the type is the first field after the "-":
+"36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue"
+*/
+func readMounts() map[string]string {
+	mounts := make(map[string]string)
+
+	content, err := os.ReadFile(mountInfoPath)
+	if err != nil {
+		return mounts
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+
+		separator := -1
+		for index, field := range fields {
+			if field == "-" {
+				separator = index
+				break
+			}
+		}
+
+		if len(fields) < 5 || separator < 0 || separator+1 >= len(fields) {
+			continue
+		}
+
+		// later mounts on the same mount point hide the earlier ones, so overwriting them is fine
+		mounts[util.FormatEntry(unescapeMountPath(fields[4]), true)] = fields[separator+1]
+	}
+
+	return mounts
+}
+
+// unescapeMountPath replaces the octal escapes (e.g. "\040" for a space) the kernel uses inside of the mountinfo
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+
+	var builder strings.Builder
+
+	for index := 0; index < len(path); index++ {
+		if path[index] == '\\' && index+3 < len(path) {
+			if char, err := strconv.ParseUint(path[index+1:index+4], 8, 8); err == nil {
+				builder.WriteByte(byte(char))
+				index += 3
+				continue
+			}
+		}
+
+		builder.WriteByte(path[index])
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+// readMounts returns no mount points, as only linux provides their filesystem types through /proc/self/mountinfo
+func readMounts() map[string]string {
+	return make(map[string]string)
+}
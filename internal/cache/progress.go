@@ -0,0 +1,261 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	PhaseIdle     string = "idle"     // the cache was never generated
+	PhaseIndexing string = "indexing" // the cache is being generated from scratch
+	PhaseUpdating string = "updating" // parts of an already generated cache are being updated
+	PhaseReady    string = "ready"    // the cache is generated and no crawl is running
+
+	progressInterval time.Duration = 250 * time.Millisecond // how often the listeners get notified during a crawl
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+var (
+	IndexProgress *Progress = &Progress{phase: PhaseIdle, history: make(map[string]int64)}
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// ProgressSnapshot is the state of the Progress at a single point in time
+type ProgressSnapshot struct {
+	Phase          string
+	Scope          string
+	CurrentRoot    string
+	DirsVisited    int64
+	EntriesIndexed int64
+	Elapsed        time.Duration
+	ETA            time.Duration
+}
+
+/*
+Progress tracks the crawl that is currently running.
+
+A crawl is split into tiers, one for every scope. For every tier we remember how many dirs it had the last time,
+so the ETA of later crawls doesn't only rely on the dirs we have discovered so far.
+*/
+type Progress struct {
+	mutex       sync.Mutex
+	phase       string
+	started     time.Time
+	tiers       []string
+	tierIndex   int
+	currentRoot string
+	history     map[string]int64
+	listeners   map[int]func(ProgressSnapshot)
+	nextID      int
+	crawl       int // the id of the crawl being tracked, counting up with every start
+	stopChan    chan bool
+
+	dirsVisited    atomic.Int64
+	entriesIndexed atomic.Int64
+	tierVisited    atomic.Int64
+	tierQueued     atomic.Int64
+}
+
+// running reports if there currently is a crawl being tracked, the mutex has to be held
+func (p *Progress) running() bool {
+	return p.phase == PhaseIndexing || p.phase == PhaseUpdating
+}
+
+// start begins tracking a crawl over the provided tiers, replacing the one tracked so far, and returns its id for finish
+func (p *Progress) start(phase string, tiers []string) int {
+	p.mutex.Lock()
+	id := p.reset(phase, tiers)
+	p.mutex.Unlock()
+
+	p.notify()
+
+	return id
+}
+
+/*
+startIfIdle begins tracking a crawl like start, but only if no other crawl is tracked right now.
+
+Checking and starting happen under the same lock, so two crawls starting at once can't both take over the Progress.
+*/
+func (p *Progress) startIfIdle(phase string, tiers []string) (int, bool) {
+	p.mutex.Lock()
+	if p.running() {
+		p.mutex.Unlock()
+		return 0, false
+	}
+
+	id := p.reset(phase, tiers)
+	p.mutex.Unlock()
+
+	p.notify()
+
+	return id, true
+}
+
+// reset resets the counters and starts reporting a new crawl, the mutex has to be held
+func (p *Progress) reset(phase string, tiers []string) int {
+	// the crawl, that gets replaced, stops reporting
+	if p.running() {
+		close(p.stopChan)
+	}
+
+	p.crawl++
+	p.phase = phase
+	p.started = time.Now()
+	p.tiers = tiers
+	p.tierIndex = 0
+	p.currentRoot = ""
+	p.stopChan = make(chan bool)
+
+	p.dirsVisited.Store(0)
+	p.entriesIndexed.Store(0)
+	p.tierVisited.Store(0)
+	p.tierQueued.Store(0)
+
+	go p.report(p.stopChan)
+
+	return p.crawl
+}
+
+// nextTier remembers the size of the finished tier and moves on to the provided one
+func (p *Progress) nextTier(tier string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for index, name := range p.tiers {
+		if name == tier {
+			p.tierIndex = index
+		}
+	}
+
+	p.tierVisited.Store(0)
+	p.tierQueued.Store(0)
+}
+
+// endTier stores how many dirs the provided tier had, to estimate later crawls
+func (p *Progress) endTier(tier string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.history[tier] = p.tierVisited.Load()
+}
+
+// finish stops tracking the crawl with the id and notifies the listeners one last time. If it already finished or got replaced, nothing happens
+func (p *Progress) finish(id int) {
+	p.mutex.Lock()
+	if id != p.crawl || !p.running() {
+		p.mutex.Unlock()
+		return
+	}
+
+	p.phase = PhaseReady
+	p.currentRoot = ""
+	close(p.stopChan)
+	p.mutex.Unlock()
+
+	p.notify()
+}
+
+// visited counts a dir as read for the provided root
+func (p *Progress) visited(root string) {
+	p.dirsVisited.Add(1)
+	p.tierVisited.Add(1)
+
+	p.mutex.Lock()
+	p.currentRoot = root
+	p.mutex.Unlock()
+}
+
+// Snapshot returns the current state of the Progress
+func (p *Progress) Snapshot() ProgressSnapshot {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	snapshot := ProgressSnapshot{
+		Phase:          p.phase,
+		CurrentRoot:    p.currentRoot,
+		DirsVisited:    p.dirsVisited.Load(),
+		EntriesIndexed: p.entriesIndexed.Load(),
+	}
+
+	if !p.running() {
+		return snapshot
+	}
+
+	snapshot.Scope = p.tiers[p.tierIndex]
+	snapshot.Elapsed = time.Since(p.started)
+
+	// the current tier has at least as many dirs as we have already discovered in it, or as it had the last time
+	remaining := max(p.tierQueued.Load(), p.history[p.tiers[p.tierIndex]]) - p.tierVisited.Load()
+	for _, tier := range p.tiers[p.tierIndex+1:] {
+		remaining += p.history[tier]
+	}
+
+	// without having read a single dir we can't know how fast we are
+	if snapshot.DirsVisited > 0 && remaining > 0 {
+		perDir := snapshot.Elapsed / time.Duration(snapshot.DirsVisited)
+		snapshot.ETA = perDir * time.Duration(remaining)
+	}
+
+	return snapshot
+}
+
+// Subscribe adds a listener that gets called with a new ProgressSnapshot during every crawl, it returns a function to remove the listener again
+func (p *Progress) Subscribe(listener func(ProgressSnapshot)) func() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.listeners == nil {
+		p.listeners = make(map[int]func(ProgressSnapshot))
+	}
+
+	id := p.nextID
+	p.nextID++
+	p.listeners[id] = listener
+
+	return func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		delete(p.listeners, id)
+	}
+}
+
+// notify calls all listeners with the current ProgressSnapshot
+func (p *Progress) notify() {
+	snapshot := p.Snapshot()
+
+	p.mutex.Lock()
+	listeners := make([]func(ProgressSnapshot), 0, len(p.listeners))
+	for _, listener := range p.listeners {
+		listeners = append(listeners, listener)
+	}
+	p.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(snapshot)
+	}
+}
+
+// report notifies the listeners in a fixed interval until the stopChan gets closed
+func (p *Progress) report(stopChan chan bool) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.notify()
+		case <-stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	ErrorPermission string = "permission" // we aren't allowed to read the folder
+	ErrorNotExist   string = "not exist"  // the folder vanished, or a root doesn't exist
+	ErrorTimeout    string = "timeout"    // reading the folder took longer than the ReadTimeout
+	ErrorOther      string = "other"      // any other error while reading the folder
+
+	ExcludedByPattern  string = "exclude pattern"  // matched by the ExcludePatterns
+	ExcludedByIgnore   string = "ignore file"      // matched by a .gitignore, .ignore or .bwsignore file
+	ExcludedByName     string = "exclude dir name" // the name is in the ExcludeDirsByName
+	ExcludedDir        string = "exclude dir"      // the folder is in the ExcludeDirs
+	ExcludedFromScope  string = "scope exclude"    // the folder is in the excludes of the scope
+	ExcludedRoot       string = "root of a scope"  // the folder is the root of a scope, so it's only crawled from there
+	ExcludedFSType     string = "filesystem type"  // the folder is the mount point of an excluded filesystem type
+	ExcludedFilesystem string = "other filesystem" // the folder is on another filesystem than its root
+	ExcludedSymlink    string = "symlink policy"   // the folder is a link, that the symlink policy doesn't follow
+	ExcludedVisited    string = "already crawled"  // the folder was already crawled by another path
+	ExcludedUnreadable string = "unreadable"       // the folder vanished or couldn't be identified before we could crawl it
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+var (
+	errReadTimeout error = errors.New("read timed out")
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// CrawlError is a folder that couldn't be read during the crawl of a scope
+type CrawlError struct {
+	Path string
+	Kind string
+	Err  string
+	Time time.Time
+}
+
+/*
+crawlReport collects the folders that couldn't be read and the entries that were left out during a crawl.
+
+Only the top most excluded entry gets recorded, as the crawl never sees what's inside of an excluded folder.
+Excluded files only get counted, as a pattern like "*.o" can leave out more files than we'd want to keep in memory.
+*/
+type crawlReport struct {
+	mutex          sync.Mutex
+	errors         []CrawlError
+	excluded       map[string]string
+	excludedCounts map[string]int
+}
+
+// newCrawlReport returns a pointer to an empty crawlReport
+func newCrawlReport() *crawlReport {
+	return &crawlReport{errors: []CrawlError{}, excluded: make(map[string]string), excludedCounts: make(map[string]int)}
+}
+
+// addError records that the folder at dirPath couldn't be read because of the err
+func (report *crawlReport) addError(dirPath string, err error) {
+	kind := ErrorOther
+
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		kind = ErrorPermission
+	case errors.Is(err, fs.ErrNotExist):
+		kind = ErrorNotExist
+	case errors.Is(err, errReadTimeout):
+		kind = ErrorTimeout
+	}
+
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.errors = append(report.errors, CrawlError{Path: dirPath, Kind: kind, Err: err.Error(), Time: time.Now()})
+}
+
+// addExcluded counts that the entry at entryPath was left out for the reason, folders (ending with a "/") get recorded as well
+func (report *crawlReport) addExcluded(entryPath string, reason string) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.excludedCounts[reason]++
+
+	if strings.HasSuffix(entryPath, "/") {
+		report.excluded[entryPath] = reason
+	}
+}
+
+// setReport stores the errors and excluded entries of the report in the scope of the fs, sorted by their path
+func (fs *Filesystem) setReport(scopeName string, report *crawlReport) {
+	sort.Slice(report.errors, func(i, j int) bool {
+		return report.errors[i].Path < report.errors[j].Path
+	})
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		scopeCache.Errors = report.errors
+		scopeCache.Excluded = report.excluded
+		scopeCache.ExcludedCounts = report.excludedCounts
+	}
+}
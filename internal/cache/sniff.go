@@ -0,0 +1,290 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	sniffLength int = 512 // the amount of bytes read from the start of a file, the same as http.DetectContentType looks at
+
+	peHeaderOffset int = 0x3c // where the DOS header of a portable executable stores the offset of the "PE\0\0" header
+
+	MIMEDirectory string = "inode/directory"
+	MIMEEmpty     string = "inode/x-empty"
+)
+
+// magicNumber is a signature at a fixed offset of a file, that http.DetectContentType doesn't know about
+type magicNumber struct {
+	offset    int
+	signature []byte
+	mime      string
+}
+
+// magicNumbers are checked in order before http.DetectContentType
+var magicNumbers = []magicNumber{
+	{0, []byte("\x7fELF"), "application/x-executable"},
+	{0, []byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{0, []byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte("\xfd7zXZ\x00"), "application/x-xz"},
+	{0, []byte("\x28\xb5\x2f\xfd"), "application/zstd"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{0, []byte("fLaC"), "audio/flac"},
+	{4, []byte("ftypheic"), "image/heic"},
+	{257, []byte("ustar"), "application/x-tar"},
+}
+
+// shebangMIMEs are the types of scripts by the name of their interpreter, without a version number
+var shebangMIMEs = map[string]string{
+	"sh":     "text/x-shellscript",
+	"bash":   "text/x-shellscript",
+	"dash":   "text/x-shellscript",
+	"zsh":    "text/x-shellscript",
+	"ksh":    "text/x-shellscript",
+	"fish":   "text/x-shellscript",
+	"python": "text/x-python",
+	"perl":   "text/x-perl",
+	"ruby":   "text/x-ruby",
+	"node":   "text/javascript",
+	"deno":   "text/javascript",
+	"php":    "text/x-php",
+	"lua":    "text/x-lua",
+	"pwsh":   "text/x-powershell",
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// mimeRecord is the sniffed MIME type of a file, together with the size and modification time it had back then
+type mimeRecord struct {
+	mime    string
+	size    int64
+	modTime time.Time
+}
+
+/*
+sniffer detects the MIME types of files from their first bytes during a crawl.
+
+Files that haven't changed since the previous crawl keep their type without being read again.
+All other files wait for the rateLimiter, so the sniffing doesn't cause more I/O than the SniffRate allows.
+*/
+type sniffer struct {
+	limiter  *rateLimiter
+	previous map[string]mimeRecord
+
+	mutex   sync.Mutex
+	records map[string]mimeRecord
+}
+
+// newSniffer returns a pointer to a sniffer, that reuses the previous records
+func newSniffer(previous map[string]mimeRecord, limiter *rateLimiter) *sniffer {
+	if previous == nil {
+		previous = make(map[string]mimeRecord)
+	}
+
+	return &sniffer{
+		limiter:  limiter,
+		previous: previous,
+		records:  make(map[string]mimeRecord),
+	}
+}
+
+// sniff returns the MIME type of the file at filePath, or an empty string if it couldn't be read
+func (s *sniffer) sniff(filePath string, info os.FileInfo) string {
+	record, ok := s.previous[filePath]
+	if !ok || record.size != info.Size() || !record.modTime.Equal(info.ModTime()) {
+		s.limiter.wait()
+
+		mimeType, err := sniffFile(filePath)
+		if err != nil {
+			return ""
+		}
+
+		record = mimeRecord{mime: mimeType, size: info.Size(), modTime: info.ModTime()}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[filePath] = record
+
+	return record.mime
+}
+
+// sniffFile reads the start of the file at filePath and detects its MIME type
+func sniffFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, sniffLength)
+	length, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return DetectMIME(buffer[:length]), nil
+}
+
+/*
+DetectMIME returns the MIME type of the data from the start of a file, without any parameters like the charset.
+
+Scripts are detected by their shebang line, binaries and archives by their magic numbers and everything else by http.DetectContentType.
+*/
+func DetectMIME(data []byte) string {
+	if len(data) < 1 {
+		return MIMEEmpty
+	}
+
+	if bytes.HasPrefix(data, []byte("#!")) {
+		return shebangMIME(data)
+	}
+
+	for _, magic := range magicNumbers {
+		if bytes.HasPrefix(data[min(magic.offset, len(data)):], magic.signature) {
+			return magic.mime
+		}
+	}
+
+	if isPortableExecutable(data) {
+		return "application/vnd.microsoft.portable-executable"
+	}
+
+	mimeType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+
+	return mimeType
+}
+
+/*
+isPortableExecutable checks if the data starts with a DOS header, that points to a "PE\0\0" header.
+
+"MZ" alone is too short of a signature, as plenty of text files start with it. A header beyond the sniffed bytes isn't found.
+*/
+func isPortableExecutable(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("MZ")) || len(data) < peHeaderOffset+4 {
+		return false
+	}
+
+	headerOffset := uint64(binary.LittleEndian.Uint32(data[peHeaderOffset:]))
+	if headerOffset+4 > uint64(len(data)) {
+		return false
+	}
+
+	return bytes.HasPrefix(data[headerOffset:], []byte("PE\x00\x00"))
+}
+
+// shebangMIME returns the MIME type of a script from the interpreter in its shebang line (e.g. "#!/usr/bin/env python3")
+func shebangMIME(data []byte) string {
+	line, _, _ := bytes.Cut(data[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+
+	// env only starts the actual interpreter, which might have some flags in front of it
+	for len(fields) > 0 && (filepath.Base(fields[0]) == "env" || strings.HasPrefix(fields[0], "-")) {
+		fields = fields[1:]
+	}
+
+	if len(fields) < 1 {
+		return "text/x-script"
+	}
+
+	interpreter := strings.TrimRight(filepath.Base(fields[0]), "0123456789.")
+	if mimeType, ok := shebangMIMEs[interpreter]; ok {
+		return mimeType
+	}
+
+	return "text/x-script"
+}
+
+/*
+MIMEType returns the sniffed MIME type of the entry.
+
+If it wasn't sniffed, the type gets guessed from the extension. Folders always are "inode/directory".
+*/
+func (entry *Entry) MIMEType() string {
+	if entry.Extension == "Folder" {
+		return MIMEDirectory
+	}
+
+	if len(entry.MIME) > 0 {
+		return entry.MIME
+	}
+
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(entry.FullName)), ";")
+
+	return mimeType
+}
+
+// mimeRecords returns the sniffed records of the last crawl of the scope
+func (fs *Filesystem) mimeRecords(scopeName string) map[string]mimeRecord {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		return scopeCache.mimes
+	}
+
+	return nil
+}
+
+// setMIMERecords stores the sniffed records of a crawl in the scope of the fs, so the next crawl can reuse them
+func (fs *Filesystem) setMIMERecords(scopeName string, records map[string]mimeRecord) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if scopeCache, ok := fs.Scopes[scopeName]; ok {
+		scopeCache.mimes = records
+	}
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// rateLimiter spreads out calls evenly, so no more than the rate happen per second
+type rateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a pointer to a rateLimiter for the rate per second, a rate of 0 or less means there is no limit
+func newRateLimiter(rate int) *rateLimiter {
+	limiter := rateLimiter{}
+	if rate > 0 {
+		limiter.interval = time.Second / time.Duration(rate)
+	}
+
+	return &limiter
+}
+
+// wait blocks until the next call is allowed
+func (limiter *rateLimiter) wait() {
+	if limiter.interval <= 0 {
+		return
+	}
+
+	limiter.mutex.Lock()
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	delay := limiter.next.Sub(now)
+	limiter.next = limiter.next.Add(limiter.interval)
+	limiter.mutex.Unlock()
+
+	time.Sleep(delay)
+}
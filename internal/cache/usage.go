@@ -0,0 +1,147 @@
+// Package cache handles everything that has to do with the generation of the cache for the Search function.
+package cache
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skillptm/bws/internal/config"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// FolderUsage holds the aggregates of a folder over all files below it, including the ones inside of its sub folders
+type FolderUsage struct {
+	Path   string    `json:"path"`
+	Size   int64     `json:"size"`   // the size of all files in bytes
+	Files  int       `json:"files"`  // the amount of files
+	Newest time.Time `json:"newest"` // the last modification of the most recently modified file, unset if there are no files
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+addUsage adds the entry to the aggregates of every folder above it, up to the root it was found in.
+
+Folders only get a FolderUsage of their own, so empty folders show up as well. Links and archive members don't count,
+as neither of them take up space inside of the folder (the archive itself already does).
+*/
+func addUsage(folders map[string]*FolderUsage, entry *Entry, roots []string) {
+	root := ""
+	for _, candidate := range roots {
+		if strings.HasPrefix(entry.Path, candidate) && len(candidate) > len(root) {
+			root = candidate
+		}
+	}
+
+	if len(root) < 1 {
+		return
+	}
+
+	if entry.IsLink || len(entry.Archive) > 0 {
+		return
+	}
+
+	if entry.Extension == "Folder" {
+		folderUsage(folders, entry.Path)
+		return
+	}
+
+	for dir := parentDir(entry.Path); len(dir) >= len(root); dir = parentDir(dir) {
+		usage := folderUsage(folders, dir)
+		usage.Size += entry.Size
+		usage.Files++
+
+		if entry.ModTime.After(usage.Newest) {
+			usage.Newest = entry.ModTime
+		}
+
+		if dir == root {
+			break
+		}
+	}
+}
+
+// folderUsage returns the FolderUsage of the dir inside of the folders and adds an empty one, if there is none yet
+func folderUsage(folders map[string]*FolderUsage, dir string) *FolderUsage {
+	usage, ok := folders[dir]
+	if !ok {
+		usage = &FolderUsage{Path: dir}
+		folders[dir] = usage
+	}
+
+	return usage
+}
+
+// parentDir returns the folder the entry at entryPath is inside of, with a trailing "/"
+func parentDir(entryPath string) string {
+	dir := path.Dir(strings.TrimSuffix(entryPath, "/"))
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+
+	return dir
+}
+
+/*
+Usage returns the FolderUsage of the folder at dirPath and of the folders up to depth levels below it, sorted by their size.
+A negative depth returns all folders below it.
+
+The folder has to be inside of a scope of the fs, otherwise nothing is returned. If several scopes hold it, the one with
+the deepest root containing it is used (the first of them in the config on a tie), as that's the scope that crawls it.
+*/
+func (fs *Filesystem) Usage(dirPath string, depth int) []FolderUsage {
+	output := []FolderUsage{}
+
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+
+	var chosen *ScopeCache
+	chosenRoot := -1
+	for _, scope := range config.BWSConfig.Scopes {
+		scopeCache, ok := fs.Scopes[scope.Name]
+		if !ok {
+			continue
+		}
+
+		if _, ok := scopeCache.Folders[dirPath]; !ok {
+			continue
+		}
+
+		for _, root := range scope.Roots {
+			if strings.HasPrefix(dirPath, root) && len(root) > chosenRoot {
+				chosen, chosenRoot = scopeCache, len(root)
+			}
+		}
+	}
+
+	if chosen == nil {
+		return output
+	}
+
+	for folder, usage := range chosen.Folders {
+		if !strings.HasPrefix(folder, dirPath) {
+			continue
+		}
+
+		if depth >= 0 && strings.Count(folder[len(dirPath):], "/") > depth {
+			continue
+		}
+
+		output = append(output, *usage)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].Size != output[j].Size {
+			return output[i].Size > output[j].Size
+		}
+
+		return output[i].Path < output[j].Path
+	})
+
+	return output
+}
@@ -7,12 +7,29 @@ import (
 	"fmt"
 	"math"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/skillptm/bws/internal/ignore"
 	"github.com/skillptm/bws/internal/util"
 )
 
 // <---------------------------------------------------------------------------------------------------->
 
+const (
+	MainScope        string        = "main"           // the preset scope that holds the MainDirs
+	SecondaryScope   string        = "secondary"      // the preset scope that holds the SecondaryDirs and ExcludeSubMainDirs
+	MainRefresh      time.Duration = 3 * time.Minute  // how often the MainDirs get updated by default
+	SecondaryRefresh time.Duration = 30 * time.Minute // how often the SecondaryDirs get updated by default
+
+	SymlinksNever       string = "never"  // links are cached, but never followed
+	SymlinksWithinRoots string = "roots"  // links are only followed, if they point inside the roots of the scope they were found in
+	SymlinksAlways      string = "always" // links are always followed
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
 var BWSConfig *Config
 
 var DefaultConfig = map[string]interface{}{
@@ -38,28 +55,148 @@ var DefaultConfig = map[string]interface{}{
 		"node_modules",
 		"steamapps",
 	},
+	"excludePatterns": []string{},
+	"ignoreFiles": []string{
+		".gitignore",
+		".ignore",
+		".bwsignore",
+	},
+	"excludeFSTypes": []string{
+		"proc",
+		"sysfs",
+		"tmpfs",
+		"devtmpfs",
+		"devpts",
+		"cgroup",
+		"cgroup2",
+		"debugfs",
+		"tracefs",
+		"securityfs",
+		"fuse.*",
+	},
+	"readTimeout": 10 * time.Second,
+	"compoundExtensions": []string{
+		".tar.gz",
+		".tar.bz2",
+		".tar.xz",
+		".tar.zst",
+		".d.ts",
+		".min.js",
+		".min.css",
+	},
+	"kinds":                defaultKinds,
+	"sniffRate":            1000,
+	"contentMaxSize":       int64(1 << 20),
+	"archiveMaxSize":       int64(256 << 20),
+	"archiveMaxMembers":    10000,
+	"extractorConcurrency": 4,
+	"extractorTimeout":     5 * time.Second,
 }
 
 // <---------------------------------------------------------------------------------------------------->
 
+/*
+Scope is a named part of the cache, that gets crawled, updated and searched on its own.
+
+A folder that is the root of a scope always belongs to that scope, even if it's inside the roots of another scope.
+Scopes marked as Extended only get searched, when they're asked for explicitly or in an extended search.
+*/
+type Scope struct {
+	Name     string
+	Roots    []string
+	Excludes []string
+	Refresh  time.Duration // how often the scope gets updated in the background, 0 means never
+	Boost    int           // points added to the rank of every result from this scope
+	Extended bool
+}
+
+/*
+Config holds the settings for the cache generation and search.
+
+MainDirs, ExcludeSubMainDirs and SecondaryDirs are the presets for the MainScope and SecondaryScope, which get rebuilt by SetPresetScopes.
+*/
 type Config struct {
-	CPUThreads         int
-	MainDirs           []string
-	ExcludeSubMainDirs []string
-	SecondaryDirs      []string
-	ExcludeDirs        []string
-	ExcludeDirsByName  []string
+	CPUThreads           int
+	MainDirs             []string
+	ExcludeSubMainDirs   []string
+	SecondaryDirs        []string
+	ExcludeDirs          []string
+	ExcludeDirsByName    []string
+	ExcludePatterns      []string
+	ExcludeRules         ignore.Rules
+	UseIgnoreFiles       bool
+	IgnoreFiles          []string
+	Symlinks             string
+	OneFilesystem        bool
+	ExcludeFSTypes       []string
+	ReadTimeout          time.Duration
+	CompoundExtensions   []string
+	Kinds                map[string][]string // the extensions of the file type categories by their name
+	SniffMIME            bool
+	SniffRate            int // how many files per second may be read to detect their MIME type or index their content, 0 means there is no limit
+	IndexContent         bool
+	ContentMaxSize       int64 // the size in bytes up to which text files get their content indexed
+	IndexArchives        bool
+	ArchiveMaxSize       int64 // the size in bytes up to which archives get their members listed
+	ArchiveMaxMembers    int   // the maximum amount of members listed per archive
+	ExtractMetadata      bool
+	ExtractorConcurrency int           // how many files may be read by the extractors at once
+	ExtractorTimeout     time.Duration // how long the extractors may take for a single file, 0 means there is no limit
+	Scopes               []*Scope
 }
 
 // <---------------------------------------------------------------------------------------------------->
 
 // New creates a new Conifg struct with the values from ./configs/config.json
 func New(configMap map[string]interface{}) (*Config, error) {
-	newConfig := Config{}
+	newConfig := Config{Symlinks: SymlinksNever}
 
 	newConfig.CPUThreads = configMap["cpuThreads"].(int)
 	delete(configMap, "cpuThreads")
 
+	// the patterns aren't paths, so they don't get formatted
+	if err := newConfig.SetExcludePatterns(configMap["excludePatterns"].([]string)); err != nil {
+		return &newConfig, err
+	}
+	delete(configMap, "excludePatterns")
+
+	// the ignore files are names and not paths, so they don't get formatted
+	newConfig.IgnoreFiles = configMap["ignoreFiles"].([]string)
+	delete(configMap, "ignoreFiles")
+
+	// the filesystem types aren't paths either
+	newConfig.ExcludeFSTypes = configMap["excludeFSTypes"].([]string)
+	delete(configMap, "excludeFSTypes")
+
+	newConfig.ReadTimeout = configMap["readTimeout"].(time.Duration)
+	delete(configMap, "readTimeout")
+
+	newConfig.SetCompoundExtensions(configMap["compoundExtensions"].([]string))
+	delete(configMap, "compoundExtensions")
+
+	for name, extensions := range configMap["kinds"].(map[string][]string) {
+		newConfig.SetKind(name, extensions)
+	}
+	delete(configMap, "kinds")
+
+	newConfig.SniffRate = configMap["sniffRate"].(int)
+	delete(configMap, "sniffRate")
+
+	newConfig.ContentMaxSize = configMap["contentMaxSize"].(int64)
+	delete(configMap, "contentMaxSize")
+
+	newConfig.ArchiveMaxSize = configMap["archiveMaxSize"].(int64)
+	delete(configMap, "archiveMaxSize")
+
+	newConfig.ArchiveMaxMembers = configMap["archiveMaxMembers"].(int)
+	delete(configMap, "archiveMaxMembers")
+
+	newConfig.ExtractorConcurrency = configMap["extractorConcurrency"].(int)
+	delete(configMap, "extractorConcurrency")
+
+	newConfig.ExtractorTimeout = configMap["extractorTimeout"].(time.Duration)
+	delete(configMap, "extractorTimeout")
+
 	// populate the newConfig with properly formated paths
 	for key, value := range configMap {
 		newSlice := value.([]string)
@@ -86,5 +223,107 @@ func New(configMap map[string]interface{}) (*Config, error) {
 		}
 	}
 
+	newConfig.SetPresetScopes()
+
 	return &newConfig, nil
 }
+
+/*
+SetPresetScopes rebuilds the MainScope and SecondaryScope from the MainDirs, ExcludeSubMainDirs and SecondaryDirs.
+
+The ExcludeSubMainDirs are left out of the MainScope and become roots of the SecondaryScope instead.
+Both scopes keep their position, refresh interval and boost, if they already exist.
+*/
+func (config *Config) SetPresetScopes() {
+	mainScope := config.Scope(MainScope)
+	if mainScope == nil {
+		mainScope = &Scope{Name: MainScope, Refresh: MainRefresh}
+		config.Scopes = append([]*Scope{mainScope}, config.Scopes...)
+	}
+
+	mainScope.Roots = config.MainDirs
+	mainScope.Excludes = config.ExcludeSubMainDirs
+	mainScope.Extended = false
+
+	secondaryScope := config.Scope(SecondaryScope)
+	if secondaryScope == nil {
+		secondaryScope = &Scope{Name: SecondaryScope, Refresh: SecondaryRefresh}
+		config.Scopes = append(config.Scopes, secondaryScope)
+	}
+
+	secondaryScope.Roots = append(append([]string{}, config.SecondaryDirs...), config.ExcludeSubMainDirs...)
+	secondaryScope.Excludes = []string{}
+	secondaryScope.Extended = true
+}
+
+// SetExcludePatterns compiles the patterns into the ExcludeRules and sets both of them
+func (config *Config) SetExcludePatterns(patterns []string) error {
+	rules, err := ignore.Parse(patterns)
+	if err != nil {
+		return fmt.Errorf("couldn't parse exclude patterns; %s", err.Error())
+	}
+
+	config.ExcludePatterns = patterns
+	config.ExcludeRules = rules
+
+	return nil
+}
+
+// SetCompoundExtensions formats the extensions to be lower case with a leading "." and sorts them by length, so the longest ones get checked first
+func (config *Config) SetCompoundExtensions(extensions []string) {
+	formatted := []string{}
+
+	for _, extension := range extensions {
+		extension = strings.ToLower(extension)
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+
+		formatted = append(formatted, extension)
+	}
+
+	sort.SliceStable(formatted, func(i, j int) bool {
+		return len(formatted[i]) > len(formatted[j])
+	})
+
+	config.CompoundExtensions = formatted
+}
+
+// Scope returns the scope with the provided name, or nil if there is none
+func (config *Config) Scope(name string) *Scope {
+	for _, scope := range config.Scopes {
+		if scope.Name == name {
+			return scope
+		}
+	}
+
+	return nil
+}
+
+// SearchScopes returns the names of all scopes that get searched in a regular or an extended search
+func (config *Config) SearchScopes(extendedSearch bool) []string {
+	names := []string{}
+
+	for _, scope := range config.Scopes {
+		if scope.Extended && !extendedSearch {
+			continue
+		}
+
+		names = append(names, scope.Name)
+	}
+
+	return names
+}
+
+// Roots returns a set of the roots of all scopes
+func (config *Config) Roots() map[string]bool {
+	roots := make(map[string]bool)
+
+	for _, scope := range config.Scopes {
+		for _, root := range scope.Roots {
+			roots[root] = true
+		}
+	}
+
+	return roots
+}
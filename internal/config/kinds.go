@@ -0,0 +1,87 @@
+// Package config handles the generation of a new config with the modules default values.
+package config
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"sort"
+	"strings"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// defaultKinds are the file type categories, that can be used with "kind:" in a searchString or in the fileExtensions
+var defaultKinds = map[string][]string{
+	"video": {
+		".mp4", ".mkv", ".avi", ".mov", ".wmv", ".flv", ".webm", ".m4v", ".mpg", ".mpeg", ".3gp", ".ogv",
+	},
+	"image": {
+		".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tif", ".tiff", ".webp", ".heic", ".heif", ".avif", ".svg", ".ico",
+		".psd", ".raw", ".cr2", ".nef", ".arw", ".dng",
+	},
+	"audio": {
+		".mp3", ".wav", ".flac", ".aac", ".ogg", ".oga", ".opus", ".m4a", ".wma", ".aiff", ".mid", ".midi",
+	},
+	"document": {
+		".pdf", ".doc", ".docx", ".odt", ".rtf", ".txt", ".md", ".tex", ".xls", ".xlsx", ".ods", ".csv",
+		".ppt", ".pptx", ".odp", ".epub",
+	},
+	"archive": {
+		".zip", ".rar", ".7z", ".tar", ".gz", ".bz2", ".xz", ".zst", ".tgz", ".tar.gz", ".tar.bz2", ".tar.xz",
+		".tar.zst", ".iso", ".cab",
+	},
+	"code": {
+		".go", ".py", ".js", ".min.js", ".jsx", ".ts", ".d.ts", ".tsx", ".java", ".kt", ".scala", ".c", ".h",
+		".cpp", ".hpp", ".cc", ".cs", ".rs", ".rb", ".php", ".swift", ".lua", ".sh", ".ps1", ".html", ".css",
+		".min.css", ".json", ".yaml", ".yml", ".toml", ".xml", ".sql",
+	},
+	"executable": {
+		".exe", ".msi", ".bat", ".cmd", ".com", ".appimage", ".apk", ".deb", ".rpm", ".dmg", ".jar", ".run",
+	},
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// SetKind adds the kind to the config or replaces the extensions of an existing one, the extensions get formatted like the fileExtensions of a search
+func (config *Config) SetKind(name string, extensions []string) {
+	formatted := []string{}
+
+	for _, extension := range extensions {
+		extension = strings.ToLower(extension)
+
+		switch {
+		case extension == "file" || extension == "folder":
+			// ensure "File"/"Folder" have the right case
+			extension = "F" + extension[1:]
+		case !strings.HasPrefix(extension, "."):
+			extension = "." + extension
+		}
+
+		formatted = append(formatted, extension)
+	}
+
+	if config.Kinds == nil {
+		config.Kinds = make(map[string][]string)
+	}
+
+	config.Kinds[strings.ToLower(name)] = formatted
+}
+
+// Kind returns the extensions of the kind with the provided name (case insensitive), or false if there is none
+func (config *Config) Kind(name string) ([]string, bool) {
+	extensions, ok := config.Kinds[strings.ToLower(name)]
+	return extensions, ok
+}
+
+// KindNames returns the names of all kinds sorted alphabetically
+func (config *Config) KindNames() []string {
+	names := []string{}
+
+	for name := range config.Kinds {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
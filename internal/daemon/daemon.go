@@ -0,0 +1,140 @@
+// Package daemon serves the cache of a single process to other processes over a unix domain socket (a named pipe on Windows).
+package daemon
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/skillptm/bws"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	dialTimeout time.Duration = 200 * time.Millisecond // how long we try to reach a daemon, before we assume there is none
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Serve answers the Requests of every connection to the listener, until the listener gets closed.
+
+The cache of this process gets generated right away, so it's ready for the first search.
+*/
+func Serve(listener net.Listener) error {
+	go bws.ForceUpdateCache()
+
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		go handle(conn)
+	}
+}
+
+// handle reads the Request from the conn, answers it and closes the conn afterwards
+func handle(conn net.Conn) {
+	defer conn.Close()
+
+	request := Request{}
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		json.NewEncoder(conn).Encode(Reply{Error: fmt.Sprintf("couldn't read the request; %s", err.Error())})
+		return
+	}
+
+	json.NewEncoder(conn).Encode(answer(&request, watchClose(conn)))
+}
+
+/*
+watchClose returns a break channel, that receives something once the client closes the conn.
+
+The client never sends anything after its Request, so a read only fails once the client is gone.
+The channel gets closed by stop, so the search that listened on it doesn't wait forever.
+*/
+func watchClose(conn net.Conn) *breaker {
+	newBreaker := breaker{breakChan: make(chan bool, 1)}
+
+	go func() {
+		buffer := make([]byte, 64)
+		for {
+			if _, err := conn.Read(buffer); err != nil {
+				break
+			}
+		}
+
+		newBreaker.mutex.Lock()
+		defer newBreaker.mutex.Unlock()
+
+		if !newBreaker.stopped {
+			newBreaker.breakChan <- true
+		}
+	}()
+
+	return &newBreaker
+}
+
+// breaker is a break channel, that can be closed once it's not listened on anymore, even while the client could still close the conn
+type breaker struct {
+	mutex     sync.Mutex
+	breakChan chan bool
+	stopped   bool
+}
+
+// stop closes the breakChan, afterwards nothing gets sent into it anymore
+func (breaker *breaker) stop() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.stopped = true
+	close(breaker.breakChan)
+}
+
+// answer runs the Request and returns its Reply, a search can be stopped by the breaker
+func answer(request *Request, breaker *breaker) Reply {
+	defer breaker.stop()
+
+	switch request.Method {
+	case MethodSearch:
+		if request.Search == nil {
+			return Reply{Error: "a search needs its search parameters"}
+		}
+
+		params := request.Search
+		if params.UseScopes {
+			response, _ := bws.GoSearchScopesWithBreak(params.SearchString, params.FileExtensions, params.Scopes, breaker.breakChan)
+			return Reply{Response: response}
+		}
+
+		response, _ := bws.GoDetailedSearchWithBreak(params.SearchString, params.FileExtensions, params.Extended, breaker.breakChan)
+		return Reply{Response: response}
+	case MethodReady:
+		<-bws.IndexReady()
+		return Reply{}
+	case MethodStatus:
+		status := bws.GetIndexStatus()
+		return Reply{Status: &status}
+	case MethodStats:
+		return Reply{Stats: bws.Stats()}
+	case MethodBuild:
+		bws.ForceUpdateCache()
+		return Reply{Stats: bws.Stats()}
+	case MethodUpdate:
+		if err := bws.UpdateScopes(request.Scopes); err != nil {
+			return Reply{Error: err.Error()}
+		}
+
+		return Reply{Stats: bws.Stats()}
+	}
+
+	return Reply{Error: fmt.Sprintf("unknown method '%s'", request.Method)}
+}
@@ -0,0 +1,434 @@
+//go:build windows
+
+// Package daemon serves the cache of a single process to other processes over a unix domain socket (a named pipe on Windows).
+package daemon
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	pipeAccessDuplex       uint32 = 0x00000003 // PIPE_ACCESS_DUPLEX
+	pipeFirstInstance      uint32 = 0x00080000 // FILE_FLAG_FIRST_PIPE_INSTANCE, creating the pipe fails if it exists already
+	pipeRejectRemote       uint32 = 0x00000008 // PIPE_REJECT_REMOTE_CLIENTS, the pipe is always in byte mode
+	pipeUnlimitedInstances uint32 = 255
+	pipeBufferSize         uint32 = 64 * 1024
+	securityIdentification uint32 = 0x00110000 // SECURITY_SQOS_PRESENT | SECURITY_IDENTIFICATION, so the pipe can't impersonate its clients
+	sddlRevision           uint32 = 1
+	seKernelObject         uint32 = 6
+	ownerInformation       uint32 = 1
+
+	errorPipeBusy         syscall.Errno = 231
+	errorPipeNotConnected syscall.Errno = 233
+	errorPipeConnected    syscall.Errno = 535
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	advapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procCreateNamedPipe     = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe    = kernel32.NewProc("ConnectNamedPipe")
+	procWaitNamedPipe       = kernel32.NewProc("WaitNamedPipeW")
+	procCreateEvent         = kernel32.NewProc("CreateEventW")
+	procGetOverlappedResult = kernel32.NewProc("GetOverlappedResult")
+	procStringToDescriptor  = advapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+	procGetSecurityInfo     = advapi32.NewProc("GetSecurityInfo")
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// pipeAddr is the name of a named pipe
+type pipeAddr string
+
+// pipeListener accepts the clients of a named pipe, every client gets its own instance of the pipe
+type pipeListener struct {
+	path       string
+	attributes syscall.SecurityAttributes // only allow the user, that created the pipe, to connect
+	mutex      sync.Mutex
+	next       *pipeConn // the instance the next client connects to
+	closed     bool
+}
+
+/*
+pipeConn is a single instance of a named pipe, opened for overlapped io.
+
+Every operation of an overlapped handle runs on its own, so a read that waits for the client to disconnect doesn't block the writes,
+and Close can cancel whatever is still running.
+*/
+type pipeConn struct {
+	handle  syscall.Handle
+	path    string
+	mutex   sync.Mutex
+	pending sync.WaitGroup // the operations, that have to end before the handle can be closed
+	closed  bool
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// defaultSocketPath returns "\\.\pipe\bws-<sid of the user>", as windows has no uid
+func defaultSocketPath() string {
+	sid, err := currentUserSID()
+	if err != nil {
+		return `\\.\pipe\bws`
+	}
+
+	return `\\.\pipe\bws-` + sid
+}
+
+// currentUserSID returns the security identifier of the user running this process
+func currentUserSID() (string, error) {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return "", err
+	}
+	defer token.Close()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+
+	return user.User.Sid.String()
+}
+
+/*
+Listen returns a listener on the named pipe at socketPath, that only the user running the daemon can connect to.
+
+Other machines on the network are always rejected. If the pipe exists already, another daemon is listening on it, which is an error.
+*/
+func Listen(socketPath string) (net.Listener, error) {
+	sid, err := currentUserSID()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the user of the daemon; %s", err.Error())
+	}
+
+	// a protected DACL, that grants the user everything and everyone else nothing
+	sddl, err := syscall.UTF16PtrFromString("D:P(A;;GA;;;" + sid + ")")
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptor uintptr
+	if result, _, err := procStringToDescriptor.Call(uintptr(unsafe.Pointer(sddl)), uintptr(sddlRevision), uintptr(unsafe.Pointer(&descriptor)), 0); result == 0 {
+		return nil, fmt.Errorf("couldn't create the security descriptor of %s; %s", socketPath, err.Error())
+	}
+
+	newListener := &pipeListener{path: socketPath}
+	newListener.attributes.Length = uint32(unsafe.Sizeof(newListener.attributes))
+	newListener.attributes.SecurityDescriptor = descriptor
+
+	handle, err := newListener.create(true)
+	if err != nil {
+		syscall.LocalFree(syscall.Handle(descriptor))
+
+		if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+			return nil, fmt.Errorf("a daemon is already listening on %s", socketPath)
+		}
+
+		return nil, fmt.Errorf("couldn't listen on %s; %s", socketPath, err.Error())
+	}
+
+	newListener.next = &pipeConn{handle: handle, path: socketPath}
+
+	return newListener, nil
+}
+
+/*
+Dial connects to the daemon listening on the named pipe at socketPath, giving up after the timeout.
+
+The pipe has to be owned by the user, otherwise another user could have created it first to pose as the daemon and answer the searches.
+*/
+func Dial(socketPath string, timeout time.Duration) (net.Conn, error) {
+	pathPointer, err := syscall.UTF16PtrFromString(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		handle, err := syscall.CreateFile(pathPointer, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_OVERLAPPED|securityIdentification, 0)
+		if err == nil {
+			if err := checkOwner(handle); err != nil {
+				syscall.CloseHandle(handle)
+				return nil, fmt.Errorf("couldn't trust the pipe %s; %s", socketPath, err.Error())
+			}
+
+			return &pipeConn{handle: handle, path: socketPath}, nil
+		}
+
+		// every instance of the pipe is busy, so wait for the daemon to create the next one
+		remaining := time.Until(deadline)
+		if !errors.Is(err, errorPipeBusy) || remaining <= 0 {
+			return nil, err
+		}
+
+		procWaitNamedPipe.Call(uintptr(unsafe.Pointer(pathPointer)), uintptr(max(remaining.Milliseconds(), 1)))
+	}
+}
+
+// checkOwner returns an error, if the pipe of the handle isn't owned by the user running this process
+func checkOwner(handle syscall.Handle) error {
+	var owner *syscall.SID
+	var descriptor uintptr
+	if result, _, _ := procGetSecurityInfo.Call(uintptr(handle), uintptr(seKernelObject), uintptr(ownerInformation), uintptr(unsafe.Pointer(&owner)), 0, 0, 0, uintptr(unsafe.Pointer(&descriptor))); result != 0 {
+		return syscall.Errno(result)
+	}
+	defer syscall.LocalFree(syscall.Handle(descriptor))
+
+	ownerSID, err := owner.String()
+	if err != nil {
+		return err
+	}
+
+	userSID, err := currentUserSID()
+	if err != nil {
+		return err
+	}
+
+	if ownerSID != userSID {
+		return errors.New("it belongs to another user")
+	}
+
+	return nil
+}
+
+// create creates a new instance of the pipe, first makes sure it's the only one
+func (listener *pipeListener) create(first bool) (syscall.Handle, error) {
+	pathPointer, err := syscall.UTF16PtrFromString(listener.path)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+
+	flags := pipeAccessDuplex | syscall.FILE_FLAG_OVERLAPPED
+	if first {
+		flags |= pipeFirstInstance
+	}
+
+	handle, _, err := procCreateNamedPipe.Call(
+		uintptr(unsafe.Pointer(pathPointer)), uintptr(flags), uintptr(pipeRejectRemote), uintptr(pipeUnlimitedInstances),
+		uintptr(pipeBufferSize), uintptr(pipeBufferSize), 0, uintptr(unsafe.Pointer(&listener.attributes)),
+	)
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, err
+	}
+
+	return syscall.Handle(handle), nil
+}
+
+// Accept waits for the next client and returns its instance of the pipe
+func (listener *pipeListener) Accept() (net.Conn, error) {
+	listener.mutex.Lock()
+	if listener.closed {
+		listener.mutex.Unlock()
+		return nil, net.ErrClosed
+	}
+
+	if listener.next == nil {
+		handle, err := listener.create(false)
+		if err != nil {
+			listener.mutex.Unlock()
+			return nil, fmt.Errorf("couldn't create a new instance of %s; %s", listener.path, err.Error())
+		}
+
+		listener.next = &pipeConn{handle: handle, path: listener.path}
+	}
+
+	conn := listener.next
+	listener.mutex.Unlock()
+
+	_, err := conn.do(func(overlapped *syscall.Overlapped) error {
+		if result, _, err := procConnectNamedPipe.Call(uintptr(conn.handle), uintptr(unsafe.Pointer(overlapped))); result == 0 {
+			return err
+		}
+
+		return nil
+	})
+
+	listener.mutex.Lock()
+	listener.next = nil
+	closed := listener.closed
+	listener.mutex.Unlock()
+
+	// a client, that connected between the creation of the instance and the wait for it, is connected as well
+	if closed || (err != nil && !errors.Is(err, errorPipeConnected)) {
+		conn.Close()
+
+		if closed {
+			return nil, net.ErrClosed
+		}
+
+		return nil, fmt.Errorf("couldn't accept a client on %s; %s", listener.path, err.Error())
+	}
+
+	return conn, nil
+}
+
+// Close stops the listener, an Accept waiting for a client returns net.ErrClosed. The clients, that are connected already, aren't affected
+func (listener *pipeListener) Close() error {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+
+	if listener.closed {
+		return nil
+	}
+
+	listener.closed = true
+
+	if listener.next != nil {
+		listener.next.Close()
+	}
+
+	syscall.LocalFree(syscall.Handle(listener.attributes.SecurityDescriptor))
+
+	return nil
+}
+
+// Addr returns the name of the pipe
+func (listener *pipeListener) Addr() net.Addr {
+	return pipeAddr(listener.path)
+}
+
+/*
+do starts the operation with a new overlapped, waits for it to end and returns how many bytes it transferred.
+
+The operation gets started while holding the mutex, so Close either cancels it or it doesn't start at all.
+*/
+func (conn *pipeConn) do(operation func(overlapped *syscall.Overlapped) error) (uint32, error) {
+	event, _, err := procCreateEvent.Call(0, 1, 0, 0)
+	if event == 0 {
+		return 0, err
+	}
+	defer syscall.CloseHandle(syscall.Handle(event))
+
+	overlapped := &syscall.Overlapped{HEvent: syscall.Handle(event)}
+
+	conn.mutex.Lock()
+	if conn.closed {
+		conn.mutex.Unlock()
+		return 0, net.ErrClosed
+	}
+
+	conn.pending.Add(1)
+	defer conn.pending.Done()
+
+	err = operation(overlapped)
+	conn.mutex.Unlock()
+
+	if err != nil && !errors.Is(err, syscall.ERROR_IO_PENDING) {
+		return 0, err
+	}
+
+	var transferred uint32
+	if result, _, err := procGetOverlappedResult.Call(uintptr(conn.handle), uintptr(unsafe.Pointer(overlapped)), uintptr(unsafe.Pointer(&transferred)), 1); result == 0 {
+		if errors.Is(err, syscall.ERROR_OPERATION_ABORTED) {
+			return transferred, net.ErrClosed
+		}
+
+		return transferred, err
+	}
+
+	return transferred, nil
+}
+
+// Read reads from the pipe, once the other side closed it io.EOF gets returned
+func (conn *pipeConn) Read(buffer []byte) (int, error) {
+	if len(buffer) < 1 {
+		return 0, nil
+	}
+
+	read, err := conn.do(func(overlapped *syscall.Overlapped) error {
+		return syscall.ReadFile(conn.handle, buffer, nil, overlapped)
+	})
+
+	if errors.Is(err, syscall.ERROR_BROKEN_PIPE) || errors.Is(err, errorPipeNotConnected) {
+		return int(read), io.EOF
+	}
+
+	return int(read), err
+}
+
+// Write writes all of the data into the pipe
+func (conn *pipeConn) Write(data []byte) (int, error) {
+	written := 0
+
+	for written < len(data) {
+		count, err := conn.do(func(overlapped *syscall.Overlapped) error {
+			return syscall.WriteFile(conn.handle, data[written:], nil, overlapped)
+		})
+
+		written += int(count)
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// Close cancels the operations, that are still running, and closes the handle once they ended
+func (conn *pipeConn) Close() error {
+	conn.mutex.Lock()
+	if conn.closed {
+		conn.mutex.Unlock()
+		return nil
+	}
+
+	conn.closed = true
+	syscall.CancelIoEx(conn.handle, nil)
+	conn.mutex.Unlock()
+
+	conn.pending.Wait()
+
+	return syscall.CloseHandle(conn.handle)
+}
+
+// LocalAddr returns the name of the pipe
+func (conn *pipeConn) LocalAddr() net.Addr {
+	return pipeAddr(conn.path)
+}
+
+// RemoteAddr returns the name of the pipe, as both sides share it
+func (conn *pipeConn) RemoteAddr() net.Addr {
+	return pipeAddr(conn.path)
+}
+
+// SetDeadline isn't supported by the pipe, a stuck operation can only be stopped by closing it
+func (conn *pipeConn) SetDeadline(_ time.Time) error {
+	return errors.New("the pipe doesn't support deadlines")
+}
+
+// SetReadDeadline isn't supported by the pipe, a stuck read can only be stopped by closing it
+func (conn *pipeConn) SetReadDeadline(_ time.Time) error {
+	return errors.New("the pipe doesn't support deadlines")
+}
+
+// SetWriteDeadline isn't supported by the pipe, a stuck write can only be stopped by closing it
+func (conn *pipeConn) SetWriteDeadline(_ time.Time) error {
+	return errors.New("the pipe doesn't support deadlines")
+}
+
+// Network returns "pipe"
+func (addr pipeAddr) Network() string {
+	return "pipe"
+}
+
+// String returns the name of the pipe
+func (addr pipeAddr) String() string {
+	return string(addr)
+}
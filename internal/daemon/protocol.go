@@ -0,0 +1,69 @@
+// Package daemon serves the cache of a single process to other processes over a unix domain socket (a named pipe on Windows).
+package daemon
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+
+	"github.com/skillptm/bws"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	SocketEnv string = "BWS_SOCKET" // the environment variable, that overrides the SocketPath
+
+	MethodSearch string = "search" // run a search and return its Response
+	MethodReady  string = "ready"  // wait until the cache is complete
+	MethodStatus string = "status" // return the IndexStatus
+	MethodStats  string = "stats"  // return the ScopeStats
+	MethodBuild  string = "build"  // generate the whole cache from scratch and wait until it's done
+	MethodUpdate string = "update" // update the scopes right away and wait until it's done
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// SearchParams are the inputs of a search, if UseScopes is set the Scopes get searched, otherwise the Extended flag decides
+type SearchParams struct {
+	SearchString   string   `json:"searchString"`
+	FileExtensions []string `json:"fileExtensions"`
+	Extended       bool     `json:"extended"`
+	Scopes         []string `json:"scopes"`
+	UseScopes      bool     `json:"useScopes"`
+}
+
+/*
+Request is a single call to the daemon. Every connection carries exactly one Request and its Reply, both as a line of JSON.
+
+Closing the connection before the Reply arrived cancels the Request, a search gets stopped like with bws.GoSearchWithBreak.
+*/
+type Request struct {
+	Method string        `json:"method"`
+	Search *SearchParams `json:"search,omitempty"`
+	Scopes []string      `json:"scopes,omitempty"` // the scopes of an update
+}
+
+// Reply is the answer to a Request, only the field of its method is set, or Error if it failed
+type Reply struct {
+	Error    string           `json:"error,omitempty"`
+	Response *bws.Response    `json:"response,omitempty"`
+	Status   *bws.IndexStatus `json:"status,omitempty"`
+	Stats    []bws.ScopeStats `json:"stats,omitempty"`
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+SocketPath returns the path of the socket the daemon listens on and the clients connect to.
+
+It's the SocketEnv, if it's set, otherwise "bws-<uid>.sock" inside of $XDG_RUNTIME_DIR or the temp folder.
+On Windows the daemon listens on a named pipe instead, which is called "\\.\pipe\bws-<sid of the user>".
+*/
+func SocketPath() string {
+	if fromEnv := os.Getenv(SocketEnv); len(fromEnv) > 0 {
+		return fromEnv
+	}
+
+	return defaultSocketPath()
+}
@@ -0,0 +1,35 @@
+//go:build !unix && !windows
+
+// Package daemon serves the cache of a single process to other processes over a unix domain socket (a named pipe on Windows).
+package daemon
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+var errUnsupported error = errors.New("the daemon isn't supported on this system")
+
+// <---------------------------------------------------------------------------------------------------->
+
+// defaultSocketPath returns "bws.sock" inside of the temp folder
+func defaultSocketPath() string {
+	return filepath.Join(os.TempDir(), "bws.sock")
+}
+
+// Listen always fails, as this system has neither unix domain sockets nor named pipes
+func Listen(socketPath string) (net.Listener, error) {
+	return nil, errUnsupported
+}
+
+// Dial always fails, so the clients fall back to the own cache
+func Dial(socketPath string, timeout time.Duration) (net.Conn, error) {
+	return nil, errUnsupported
+}
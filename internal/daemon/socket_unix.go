@@ -0,0 +1,75 @@
+//go:build unix
+
+// Package daemon serves the cache of a single process to other processes over a unix domain socket (a named pipe on Windows).
+package daemon
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// defaultSocketPath returns "bws-<uid>.sock" inside of $XDG_RUNTIME_DIR or the temp folder
+func defaultSocketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if len(dir) < 1 {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("bws-%d.sock", os.Getuid()))
+}
+
+/*
+Listen returns a listener on the unix domain socket at socketPath, that only the user running the daemon can connect to.
+
+A socket file left behind by a daemon that didn't shut down properly gets removed, but if another daemon still answers on it, that's an error.
+*/
+func Listen(socketPath string) (net.Listener, error) {
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, err := net.DialTimeout("unix", socketPath, dialTimeout); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("a daemon is already listening on %s", socketPath)
+		}
+
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("couldn't remove the old socket %s; %s", socketPath, err.Error())
+		}
+	}
+
+	// the temp folder is shared by every user, so the socket has to keep the others out from the moment it exists
+	// the umask belongs to the whole process, but anything created in the meantime only ends up more restricted
+	oldMask := syscall.Umask(0o177)
+	listener, err := net.Listen("unix", socketPath)
+	syscall.Umask(oldMask)
+
+	if err != nil {
+		return nil, fmt.Errorf("couldn't listen on %s; %s", socketPath, err.Error())
+	}
+
+	return listener, nil
+}
+
+/*
+Dial connects to the daemon listening on the socket at socketPath, giving up after the timeout.
+
+The socket has to belong to the user, otherwise another user could pose as the daemon and answer the searches.
+*/
+func Dial(socketPath string, timeout time.Duration) (net.Conn, error) {
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+		return nil, fmt.Errorf("the socket %s belongs to another user", socketPath)
+	}
+
+	return net.DialTimeout("unix", socketPath, timeout)
+}
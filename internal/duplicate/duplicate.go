@@ -0,0 +1,354 @@
+// Package duplicate finds files with the same content inside of the cache.
+package duplicate
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/skillptm/bws/internal/cache"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	partialHashSize int64 = 16 << 10 // the bytes from the start and the end of a file, that get compared before the whole file
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Options are the settings for Find
+type Options struct {
+	MinSize  int64 // smaller files are ignored
+	Workers  int   // how many files get hashed at once
+	SameName bool  // find files with the same name, but different contents instead
+}
+
+// Set is a group of files with the same content, or in the SameName mode with the same name but different contents
+type Set struct {
+	Name   string   `json:"name,omitempty"` // the lower case name of the files, only set in the SameName mode
+	Hash   string   `json:"hash,omitempty"` // the hex encoded SHA-256 of the content, only set if the files have the same content
+	Size   int64    `json:"size"`           // the size of each file, in the SameName mode the size of all files together
+	Wasted int64    `json:"wasted"`         // the bytes, that could be freed by only keeping one of the files
+	Paths  []string `json:"paths"`
+}
+
+// candidate is a file that might have a duplicate
+type candidate struct {
+	path string
+	name string
+	size int64
+	info os.FileInfo
+	hash string
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Find returns the duplicate Sets of the files inside of the scope of the fs, ranked by the bytes they waste.
+
+Files are first grouped by their size, then by a hash over their start and end and finally by a hash over their whole content,
+so only files that might be duplicates get read completely. Hard links to the same file don't count as duplicates.
+Links and archive members are left out. It returns true, if it was stopped early by the stopChan.
+*/
+func Find(fs *cache.Filesystem, scopeName string, options Options, stopChan chan bool) ([]Set, bool) {
+	candidates := collect(fs, scopeName, options.MinSize)
+
+	if options.SameName {
+		return findSameName(candidates, options, stopChan)
+	}
+
+	sets := []Set{}
+
+	for _, group := range groupBy(candidates, sizeKey) {
+		// files whose size changed since the crawl are compared by their new size
+		group = restat(group)
+
+		for _, sameSize := range groupBy(group, sizeKey) {
+			sameSize = withoutHardLinks(sameSize)
+			if len(sameSize) < 2 {
+				continue
+			}
+
+			if stopped := hashAll(sameSize, options.Workers, partialHash, stopChan); stopped {
+				return []Set{}, true
+			}
+
+			for _, samePartial := range groupBy(sameSize, func(file *candidate) string { return file.hash }) {
+				// small files were already read completely
+				if samePartial[0].size > 2*partialHashSize {
+					if stopped := hashAll(samePartial, options.Workers, fullHash, stopChan); stopped {
+						return []Set{}, true
+					}
+				}
+
+				for _, sameContent := range groupBy(samePartial, func(file *candidate) string { return file.hash }) {
+					sets = append(sets, newSet(sameContent))
+				}
+			}
+		}
+	}
+
+	sortSets(sets)
+
+	return sets, false
+}
+
+// findSameName returns the Sets of files with the same name, that don't all have the same content
+func findSameName(candidates []*candidate, options Options, stopChan chan bool) ([]Set, bool) {
+	sets := []Set{}
+
+	for _, sameName := range groupBy(candidates, func(file *candidate) string { return file.name }) {
+		sameName = withoutHardLinks(restat(sameName))
+		if len(sameName) < 2 {
+			continue
+		}
+
+		// files of different sizes can't have the same content, so we only need to hash, if all of them have the same size
+		differentSizes := false
+		for _, file := range sameName {
+			differentSizes = differentSizes || file.size != sameName[0].size
+		}
+
+		if !differentSizes {
+			if stopped := hashAll(sameName, options.Workers, fullHash, stopChan); stopped {
+				return []Set{}, true
+			}
+
+			if allEqual(sameName) {
+				continue
+			}
+		}
+
+		newSameName := Set{Name: sameName[0].name}
+		for _, file := range sameName {
+			newSameName.Size += file.size
+			newSameName.Paths = append(newSameName.Paths, file.path)
+		}
+		sort.Strings(newSameName.Paths)
+
+		sets = append(sets, newSameName)
+	}
+
+	sortSets(sets)
+
+	return sets, false
+}
+
+// collect returns all files of the scope, that are at least minSize bytes large, as candidates
+func collect(fs *cache.Filesystem, scopeName string, minSize int64) []*candidate {
+	candidates := []*candidate{}
+
+	fs.RLock()
+	defer fs.RUnlock()
+
+	scopeCache, ok := fs.Scopes[scopeName]
+	if !ok {
+		return candidates
+	}
+
+	for extension, lengthMaps := range scopeCache.Entries {
+		if extension == "Folder" {
+			continue
+		}
+
+		for _, entries := range lengthMaps {
+			for _, entry := range entries {
+				if entry.IsLink || len(entry.Archive) > 0 || entry.Size < max(minSize, 1) {
+					continue
+				}
+
+				candidates = append(candidates, &candidate{path: entry.Path, name: entry.FullName, size: entry.Size})
+			}
+		}
+	}
+
+	return candidates
+}
+
+// groupBy groups the files by their key and only returns the groups with at least 2 files, the files keep their order
+func groupBy(files []*candidate, key func(file *candidate) string) [][]*candidate {
+	groups := make(map[string][]*candidate)
+	keys := []string{}
+
+	for _, file := range files {
+		fileKey := key(file)
+		if _, ok := groups[fileKey]; !ok {
+			keys = append(keys, fileKey)
+		}
+
+		groups[fileKey] = append(groups[fileKey], file)
+	}
+
+	output := [][]*candidate{}
+	for _, fileKey := range keys {
+		if len(groups[fileKey]) > 1 {
+			output = append(output, groups[fileKey])
+		}
+	}
+
+	return output
+}
+
+// sizeKey is the key for groupBy, that groups the files by their size
+func sizeKey(file *candidate) string {
+	return strconv.FormatInt(file.size, 10)
+}
+
+// restat updates the size and info of the files from the disk and drops the ones, that don't exist anymore
+func restat(files []*candidate) []*candidate {
+	output := []*candidate{}
+
+	for _, file := range files {
+		info, err := os.Stat(file.path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		file.info = info
+		file.size = info.Size()
+		output = append(output, file)
+	}
+
+	return output
+}
+
+// withoutHardLinks drops every file, that is the same file on the disk as one before it. Files that can't be identified are kept
+func withoutHardLinks(files []*candidate) []*candidate {
+	output := []*candidate{}
+	seen := make(map[cache.FileID]bool)
+
+	for _, file := range files {
+		if id, ok := cache.GetFileID(file.path, file.info); ok {
+			if seen[id] {
+				continue
+			}
+
+			seen[id] = true
+		}
+
+		output = append(output, file)
+	}
+
+	return output
+}
+
+// hashAll sets the hash of all files with the hash function, using up to workers goroutines. It returns true, if it was stopped by the stopChan
+func hashAll(files []*candidate, workers int, hash func(file *candidate) string, stopChan chan bool) bool {
+	fileChan := make(chan *candidate)
+
+	var wg sync.WaitGroup
+
+	for range max(min(workers, len(files)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for file := range fileChan {
+				file.hash = hash(file)
+			}
+		}()
+	}
+
+	stopped := false
+	for _, file := range files {
+		if len(stopChan) > 0 {
+			stopped = true
+			break
+		}
+
+		fileChan <- file
+	}
+
+	close(fileChan)
+	wg.Wait()
+
+	return stopped
+}
+
+// partialHash hashes the start and the end of the file, a file that can't be read gets a unique hash, so it never is a duplicate
+func partialHash(file *candidate) string {
+	osFile, err := os.Open(file.path)
+	if err != nil {
+		return "error " + file.path
+	}
+	defer osFile.Close()
+
+	hasher := sha256.New()
+
+	if _, err := io.Copy(hasher, io.LimitReader(osFile, partialHashSize)); err != nil {
+		return "error " + file.path
+	}
+
+	if file.size > 2*partialHashSize {
+		if _, err := osFile.Seek(-partialHashSize, io.SeekEnd); err != nil {
+			return "error " + file.path
+		}
+	}
+
+	if _, err := io.Copy(hasher, osFile); err != nil {
+		return "error " + file.path
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// fullHash hashes the whole file, a file that can't be read gets a unique hash, so it never is a duplicate
+func fullHash(file *candidate) string {
+	osFile, err := os.Open(file.path)
+	if err != nil {
+		return "error " + file.path
+	}
+	defer osFile.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, osFile); err != nil {
+		return "error " + file.path
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// allEqual checks if all files have the same hash
+func allEqual(files []*candidate) bool {
+	for _, file := range files {
+		if file.hash != files[0].hash {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newSet returns a Set of the files with the same content
+func newSet(files []*candidate) Set {
+	newDuplicates := Set{Hash: files[0].hash, Size: files[0].size, Wasted: files[0].size * int64(len(files)-1)}
+
+	for _, file := range files {
+		newDuplicates.Paths = append(newDuplicates.Paths, file.path)
+	}
+	sort.Strings(newDuplicates.Paths)
+
+	return newDuplicates
+}
+
+// sortSets sorts the sets by their wasted bytes, then by their size and then by their first path
+func sortSets(sets []Set) {
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].Wasted != sets[j].Wasted {
+			return sets[i].Wasted > sets[j].Wasted
+		}
+
+		if sets[i].Size != sets[j].Size {
+			return sets[i].Size > sets[j].Size
+		}
+
+		return sets[i].Paths[0] < sets[j].Paths[0]
+	})
+}
@@ -0,0 +1,239 @@
+// Package ignore parses and matches exclusion patterns in the syntax of .gitignore files.
+package ignore
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Rule is a single compiled pattern.
+
+Patterns without a "/" (except for a trailing one) match against the name of an entry on any level,
+all other patterns match against the whole path relative to the folder the rules belong to.
+Most patterns are either a plain name, a lone "*" or "*" followed by a plain suffix, so those are checked without a regexp.
+*/
+type Rule struct {
+	Pattern string
+	Negate  bool
+	DirOnly bool
+
+	nameOnly bool
+	matchAll bool
+	literal  string
+	suffix   string
+	regex    *regexp.Regexp
+}
+
+// Rules is an ordered list of Rule, where the last matching Rule decides if an entry is excluded
+type Rules []*Rule
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Parse compiles the lines of a .gitignore file or a list of patterns into Rules, empty lines and comments are skipped
+func Parse(lines []string) (Rules, error) {
+	rules := Rules{}
+
+	for _, line := range lines {
+		rule, err := NewRule(line)
+		if err != nil {
+			return rules, err
+		}
+
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// NewRule compiles a single pattern into a pointer to a Rule, for empty lines and comments it returns nil
+func NewRule(line string) (*Rule, error) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+
+	if len(line) < 1 || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	rule := Rule{Pattern: line}
+
+	if strings.HasPrefix(line, "!") {
+		rule.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if len(line) < 1 {
+		return nil, nil
+	}
+
+	// a leading or middle "/" anchors the pattern to the folder the rules belong to
+	rule.nameOnly = !strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	if !strings.ContainsAny(line, "*?[\\") {
+		rule.literal = line
+		return &rule, nil
+	}
+
+	// a lone "*" matches every name
+	if rule.nameOnly && line == "*" {
+		rule.matchAll = true
+		return &rule, nil
+	}
+
+	if rule.nameOnly && len(line) > 1 && strings.HasPrefix(line, "*") && !strings.ContainsAny(line[1:], "*?[\\") {
+		rule.suffix = line[1:]
+		return &rule, nil
+	}
+
+	var err error
+	rule.regex, err = regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't compile pattern '%s'; %s", rule.Pattern, err.Error())
+	}
+
+	return &rule, nil
+}
+
+// Matches checks if the relPath (separated by "/", without a trailing "/") is matched by the rule
+func (rule *Rule) Matches(relPath string, isDir bool) bool {
+	if rule.DirOnly && !isDir {
+		return false
+	}
+
+	target := relPath
+	if rule.nameOnly {
+		target = relPath[strings.LastIndex(relPath, "/")+1:]
+	}
+
+	switch {
+	case rule.matchAll:
+		return true
+	case len(rule.literal) > 0:
+		return target == rule.literal
+	case len(rule.suffix) > 0:
+		return strings.HasSuffix(target, rule.suffix)
+	default:
+		return rule.regex.MatchString(target)
+	}
+}
+
+/*
+Match checks the relPath against all rules. Matched tells if any rule matched at all, excluded what the last matching rule decided.
+
+The relPath has to be separated by "/" and may not have a trailing "/".
+*/
+func (rules Rules) Match(relPath string, isDir bool) (matched bool, excluded bool) {
+	for index := len(rules) - 1; index >= 0; index-- {
+		if rules[index].Matches(relPath, isDir) {
+			return true, !rules[index].Negate
+		}
+	}
+
+	return false, false
+}
+
+// Excluded checks if the relPath gets excluded by the rules
+func (rules Rules) Excluded(relPath string, isDir bool) bool {
+	_, excluded := rules.Match(relPath, isDir)
+	return excluded
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// trimTrailingSpaces removes trailing spaces from the line, unless they're escaped with a "\"
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
+
+// globToRegexp translates a gitignore glob into a regular expression, that matches the whole string
+func globToRegexp(glob string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+
+	for index := 0; index < len(glob); index++ {
+		char := glob[index]
+
+		switch char {
+		case '*':
+			// a "**" that is a whole path segment matches any amount of folders
+			if index+1 < len(glob) && glob[index+1] == '*' && (index == 0 || glob[index-1] == '/') {
+				if index+2 == len(glob) {
+					builder.WriteString(".*")
+					index++
+					continue
+				}
+
+				if glob[index+2] == '/' {
+					builder.WriteString("(?:.*/)?")
+					index += 2
+					continue
+				}
+			}
+
+			// any other amount of "*" matches everything but a "/"
+			for index+1 < len(glob) && glob[index+1] == '*' {
+				index++
+			}
+			builder.WriteString("[^/]*")
+		case '?':
+			builder.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[index+1:], ']')
+			// a "]" right after the "[" (or "[!") is part of the class
+			if end == 0 || (end == 1 && glob[index+1] == '!') {
+				next := strings.IndexByte(glob[index+end+2:], ']')
+				if next < 0 {
+					end = -1
+				} else {
+					end += next + 1
+				}
+			}
+
+			if end < 0 {
+				builder.WriteString("\\[")
+				continue
+			}
+
+			class := glob[index+1 : index+1+end]
+			builder.WriteString("[")
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				builder.WriteString("^/")
+				class = class[1:]
+			}
+			builder.WriteString(strings.NewReplacer("\\", "\\\\", "[", "\\[", "]", "\\]").Replace(class))
+			builder.WriteString("]")
+
+			index += end + 1
+		case '\\':
+			if index+1 < len(glob) {
+				index++
+			}
+			builder.WriteString(regexp.QuoteMeta(string(glob[index])))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	builder.WriteString("$")
+
+	return builder.String()
+}
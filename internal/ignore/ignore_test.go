@@ -0,0 +1,44 @@
+package ignore
+
+import "testing"
+
+// TestMatch makes sure the patterns match as in a .gitignore file, including the ones that consist of nothing but "*"
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		relPath  string
+		isDir    bool
+		matched  bool
+		excluded bool
+	}{
+		{name: "star file", patterns: []string{"*"}, relPath: "a/b.txt", matched: true, excluded: true},
+		{name: "star folder", patterns: []string{"*"}, relPath: "a", isDir: true, matched: true, excluded: true},
+		{name: "negated star", patterns: []string{"!*"}, relPath: "b.txt", matched: true},
+		{name: "star folder only on a folder", patterns: []string{"*/"}, relPath: "a/b", isDir: true, matched: true, excluded: true},
+		{name: "star folder only on a file", patterns: []string{"*/"}, relPath: "a/b.txt"},
+		{name: "star and negation", patterns: []string{"*", "!.gitignore"}, relPath: ".gitignore", matched: true},
+		{name: "double star", patterns: []string{"**"}, relPath: "a/b.txt", matched: true, excluded: true},
+		{name: "anchored star", patterns: []string{"/*"}, relPath: "b.txt", matched: true, excluded: true},
+		{name: "anchored star below the root", patterns: []string{"/*"}, relPath: "a/b.txt"},
+		{name: "suffix", patterns: []string{"*.tmp"}, relPath: "a/b.tmp", matched: true, excluded: true},
+		{name: "suffix no match", patterns: []string{"*.tmp"}, relPath: "a/b.txt"},
+		{name: "literal", patterns: []string{"target"}, relPath: "a/target", isDir: true, matched: true, excluded: true},
+		{name: "glob", patterns: []string{"build-*"}, relPath: "build-1", matched: true, excluded: true},
+		{name: "path", patterns: []string{"docs/**/*.md"}, relPath: "docs/a/b.md", matched: true, excluded: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := Parse(test.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			matched, excluded := rules.Match(test.relPath, test.isDir)
+			if matched != test.matched || excluded != test.excluded {
+				t.Errorf("expected matched %v and excluded %v, got %v and %v", test.matched, test.excluded, matched, excluded)
+			}
+		})
+	}
+}
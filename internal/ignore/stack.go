@@ -0,0 +1,80 @@
+// Package ignore parses and matches exclusion patterns in the syntax of .gitignore files.
+package ignore
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Stack is the chain of Rules from the ignore files of a folder and all of its parents, that were read during a crawl.
+
+As with .gitignore files, the Rules of a deeper folder take precedence over the ones of its parents.
+A nil Stack is empty and excludes nothing.
+*/
+type Stack struct {
+	parent *Stack
+	base   string
+	rules  Rules
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Push returns a new Stack with the rules for the folder base (with a trailing "/") on top, if there are no rules the stack stays the same
+func (stack *Stack) Push(base string, rules Rules) *Stack {
+	if len(rules) < 1 {
+		return stack
+	}
+
+	return &Stack{parent: stack, base: base, rules: rules}
+}
+
+/*
+Load reads the ignore files from the folder dir (with a trailing "/") and pushes their Rules onto the stack.
+
+Only the fileNames in the order they're provided are read, so later files take precedence over earlier ones.
+Lines that aren't valid patterns get skipped, the same way git does it.
+*/
+func (stack *Stack) Load(dir string, fileNames []string) *Stack {
+	rules := Rules{}
+
+	for _, fileName := range fileNames {
+		content, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			rule, err := NewRule(line)
+			if err != nil || rule == nil {
+				continue
+			}
+
+			rules = append(rules, rule)
+		}
+	}
+
+	return stack.Push(dir, rules)
+}
+
+// Excluded checks if the entryPath (separated by "/") gets excluded by the Rules of the deepest folder that has a matching Rule
+func (stack *Stack) Excluded(entryPath string, isDir bool) bool {
+	entryPath = strings.TrimSuffix(entryPath, "/")
+
+	for frame := stack; frame != nil; frame = frame.parent {
+		if !strings.HasPrefix(entryPath, frame.base) {
+			continue
+		}
+
+		if matched, excluded := frame.rules.Match(entryPath[len(frame.base):], isDir); matched {
+			return excluded
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,376 @@
+// Package metadata reads the metadata fields of media files and documents, like the date a photo was taken or the title of a PDF.
+package metadata
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	maxTagSize   int64 = 16 << 20 // tags larger than this are most likely embedded cover art, which we don't need
+	maxAtomDepth int   = 8        // the metadata of an MP4 is at most a few atoms deep
+)
+
+// id3Frames are the fields of the ID3v2.3/ID3v2.4 frames and their ID3v2.2 equivalents
+var id3Frames = map[string]string{
+	"TPE1": FieldArtist, "TP1": FieldArtist,
+	"TALB": FieldAlbum, "TAL": FieldAlbum,
+	"TIT2": FieldTitle, "TT2": FieldTitle,
+	"TYER": FieldYear, "TYE": FieldYear,
+	"TDRC": FieldYear,
+}
+
+// vorbisComments are the fields of the Vorbis comments inside of a FLAC
+var vorbisComments = map[string]string{
+	"ARTIST": FieldArtist,
+	"ALBUM":  FieldAlbum,
+	"TITLE":  FieldTitle,
+	"DATE":   FieldYear,
+}
+
+// mp4Items are the fields of the items inside of the ilst atom of an MP4
+var mp4Items = map[string]string{
+	"\xa9ART": FieldArtist,
+	"aART":    FieldArtist,
+	"\xa9alb": FieldAlbum,
+	"\xa9nam": FieldTitle,
+	"\xa9day": FieldYear,
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// readMP3 reads the ID3v2 tag at the start of an MP3 and falls back to the ID3v1 tag at its end
+func readMP3(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fields := map[string]string{}
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(file, header); err == nil && bytes.HasPrefix(header, []byte("ID3")) {
+		size := int64(syncsafe(header[6:10]))
+		if size <= maxTagSize {
+			tag := make([]byte, size)
+			if _, err := io.ReadFull(file, tag); err == nil {
+				readID3v2(tag, header[3], header[5], fields)
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return fields, nil
+	}
+
+	// the ID3v1 tag are the last 128 bytes: "TAG", title, artist, album (30 bytes each) and year (4 bytes)
+	tag := make([]byte, 128)
+	if _, err := file.Seek(-128, io.SeekEnd); err != nil {
+		return fields, nil
+	}
+	if _, err := io.ReadFull(file, tag); err != nil || !bytes.HasPrefix(tag, []byte("TAG")) {
+		return fields, nil
+	}
+
+	fields[FieldTitle] = decodeLatin1(bytes.TrimRight(tag[3:33], "\x00 "))
+	fields[FieldArtist] = decodeLatin1(bytes.TrimRight(tag[33:63], "\x00 "))
+	fields[FieldAlbum] = decodeLatin1(bytes.TrimRight(tag[63:93], "\x00 "))
+	fields[FieldYear] = decodeLatin1(bytes.TrimRight(tag[93:97], "\x00 "))
+
+	return fields, nil
+}
+
+// readID3v2 reads the text frames of an ID3v2 tag, without its header
+func readID3v2(tag []byte, version byte, flags byte, fields map[string]string) {
+	// ID3v2.2 has 3 byte ids and sizes, all later versions 4 byte ones
+	idLength, headerLength := 4, 10
+	if version == 2 {
+		idLength, headerLength = 3, 6
+	}
+
+	// skip the extended header
+	index := 0
+	if flags&0x40 != 0 && version > 2 && len(tag) >= 4 {
+		index = int(binary.BigEndian.Uint32(tag))
+		if version == 4 {
+			index = int(syncsafe(tag[:4]))
+		}
+		if version == 3 {
+			index += 4
+		}
+	}
+
+	for index+headerLength <= len(tag) {
+		id := string(tag[index : index+idLength])
+		if id[0] == 0 {
+			// the rest is padding
+			return
+		}
+
+		var size int
+		switch version {
+		case 2:
+			size = int(tag[index+3])<<16 | int(tag[index+4])<<8 | int(tag[index+5])
+		case 4:
+			size = int(syncsafe(tag[index+4 : index+8]))
+		default:
+			size = int(binary.BigEndian.Uint32(tag[index+4:]))
+		}
+
+		start := index + headerLength
+		if size < 0 || start+size > len(tag) {
+			return
+		}
+
+		if field, ok := id3Frames[id]; ok && size > 1 {
+			value := decodeID3Text(tag[start : start+size])
+			if field == FieldYear && len(value) > 4 {
+				value = value[:4]
+			}
+			fields[field] = value
+		}
+
+		index = start + size
+	}
+}
+
+// decodeID3Text turns the content of an ID3v2 text frame into a string, its first byte is the encoding
+func decodeID3Text(frame []byte) string {
+	text := frame[1:]
+
+	var value string
+	switch frame[0] {
+	case 1:
+		value = decodeUTF16(text, false)
+	case 2:
+		value = decodeUTF16(text, true)
+	case 3:
+		value = string(text)
+	default:
+		value = decodeLatin1(text)
+	}
+
+	// multiple values are separated by NUL, we only keep the first one
+	value, _, _ = strings.Cut(value, "\x00")
+
+	return value
+}
+
+// syncsafe decodes a 4 byte integer, that only uses the lower 7 bits of every byte
+func syncsafe(data []byte) uint32 {
+	return uint32(data[0]&0x7F)<<21 | uint32(data[1]&0x7F)<<14 | uint32(data[2]&0x7F)<<7 | uint32(data[3]&0x7F)
+}
+
+// readFLAC reads the Vorbis comments from the metadata blocks at the start of a FLAC
+func readFLAC(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil || string(magic) != "fLaC" {
+		return nil, errInvalid
+	}
+
+	fields := map[string]string{}
+	header := make([]byte, 4)
+
+	for {
+		if _, err := io.ReadFull(file, header); err != nil {
+			return fields, nil
+		}
+
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		if blockType == 4 && size <= maxTagSize {
+			block := make([]byte, size)
+			if _, err := io.ReadFull(file, block); err != nil {
+				return fields, nil
+			}
+
+			readVorbisComments(block, fields)
+
+			return fields, nil
+		}
+
+		if last {
+			return fields, nil
+		}
+
+		if _, err := file.Seek(size, io.SeekCurrent); err != nil {
+			return fields, nil
+		}
+	}
+}
+
+// readVorbisComments reads the "KEY=value" comments of a Vorbis comment block
+func readVorbisComments(block []byte, fields map[string]string) {
+	// readString reads a string with a little endian 32 bit length in front of it
+	readString := func() (string, bool) {
+		if len(block) < 4 {
+			return "", false
+		}
+
+		length := int64(binary.LittleEndian.Uint32(block))
+		if 4+length > int64(len(block)) {
+			return "", false
+		}
+
+		value := string(block[4 : 4+length])
+		block = block[4+length:]
+
+		return value, true
+	}
+
+	// skip the vendor string
+	if _, ok := readString(); !ok || len(block) < 4 {
+		return
+	}
+
+	count := binary.LittleEndian.Uint32(block)
+	block = block[4:]
+
+	for range count {
+		comment, ok := readString()
+		if !ok {
+			return
+		}
+
+		key, value, found := strings.Cut(comment, "=")
+		field, known := vorbisComments[strings.ToUpper(key)]
+		if !found || !known || !utf8.ValidString(value) {
+			continue
+		}
+
+		// only the first of multiple values gets kept
+		if _, ok := fields[field]; ok {
+			continue
+		}
+
+		if field == FieldYear && len(value) > 4 {
+			value = value[:4]
+		}
+		fields[field] = value
+	}
+}
+
+// readMP4 reads the items of the iTunes metadata inside of the moov/udta/meta/ilst atoms of an MP4
+func readMP4(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+
+	ilst, ok := findAtom(file, 0, info.Size(), []string{"moov", "udta", "meta", "ilst"}, 0)
+	if !ok || ilst.size > maxTagSize {
+		return fields, nil
+	}
+
+	data := make([]byte, ilst.size)
+	if _, err := file.ReadAt(data, ilst.start); err != nil {
+		return fields, nil
+	}
+
+	// every item is an atom, whose "data" atom holds 8 bytes of type and locale in front of the value
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			break
+		}
+
+		item := data[8:size]
+		if field, ok := mp4Items[string(data[4:8])]; ok && len(item) >= 16 && string(item[4:8]) == "data" {
+			dataSize := min(int(binary.BigEndian.Uint32(item)), len(item))
+			if dataSize >= 16 {
+				value := string(item[16:dataSize])
+				if field == FieldYear && len(value) > 4 {
+					value = value[:4]
+				}
+				if _, ok := fields[field]; !ok {
+					fields[field] = value
+				}
+			}
+		}
+
+		data = data[size:]
+	}
+
+	return fields, nil
+}
+
+// atom is the content of an MP4 atom, without its header
+type atom struct {
+	start int64
+	size  int64
+}
+
+// findAtom follows the path of atom types from the atoms between start and end, it returns false if an atom of the path is missing
+func findAtom(file *os.File, start int64, end int64, atomPath []string, depth int) (atom, bool) {
+	if len(atomPath) < 1 || depth > maxAtomDepth {
+		return atom{start: start, size: end - start}, len(atomPath) < 1
+	}
+
+	header := make([]byte, 16)
+
+	for offset := start; offset+8 <= end; {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return atom{}, false
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		atomType := string(header[4:8])
+		headerSize := int64(8)
+
+		switch size {
+		case 0:
+			// the atom lasts until the end
+			size = end - offset
+		case 1:
+			// the real size follows as 64 bit integer
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return atom{}, false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+
+		if size < headerSize || offset+size > end {
+			return atom{}, false
+		}
+
+		if atomType == atomPath[0] {
+			contentStart := offset + headerSize
+			// the meta atom is a full box, so it has 4 more bytes of version and flags
+			if atomType == "meta" {
+				contentStart += 4
+			}
+
+			return findAtom(file, contentStart, offset+size, atomPath[1:], depth+1)
+		}
+
+		offset += size
+	}
+
+	return atom{}, false
+}
@@ -0,0 +1,374 @@
+// Package metadata reads the metadata fields of media files and documents, like the date a photo was taken or the title of a PDF.
+package metadata
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	pdfTrailerSize int64 = 64 << 10 // the trailer with the reference to the info dictionary is at the end of a PDF
+	pdfScanLimit   int64 = 64 << 20 // how far we look for the info dictionary, if the PDF has no classic xref table
+	maxXMLSize     int64 = 1 << 20  // the metadata files inside of OOXML and EPUB files are tiny
+)
+
+var (
+	pdfInfoRef   *regexp.Regexp = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	pdfStartXref *regexp.Regexp = regexp.MustCompile(`startxref\s+(\d+)`)
+)
+
+// pdfInfoKeys are the fields of the keys inside of the info dictionary of a PDF
+var pdfInfoKeys = map[string]string{
+	"/Title":   FieldTitle,
+	"/Author":  FieldAuthor,
+	"/Subject": FieldSubject,
+}
+
+// dublinCore are the fields of the Dublin Core elements, that OOXML and EPUB use for their metadata
+type dublinCore struct {
+	Title    []string `xml:"title"`
+	Creators []string `xml:"creator"`
+	Subjects []string `xml:"subject"`
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// readPDF reads the title, author and subject from the info dictionary of a PDF
+func readPDF(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	trailer := make([]byte, min(pdfTrailerSize, info.Size()))
+	if _, err := file.ReadAt(trailer, info.Size()-int64(len(trailer))); err != nil {
+		return nil, err
+	}
+
+	// the last trailer is the newest one
+	refs := pdfInfoRef.FindAllSubmatch(trailer, -1)
+	if len(refs) < 1 {
+		return map[string]string{}, nil
+	}
+	object := string(refs[len(refs)-1][1]) + " " + string(refs[len(refs)-1][2]) + " obj"
+
+	offset, ok := pdfObjectOffset(file, trailer, refs[len(refs)-1][1])
+	if !ok {
+		offset, ok = scanFor(file, []byte(object), pdfScanLimit)
+	}
+	if !ok {
+		return map[string]string{}, nil
+	}
+
+	data := make([]byte, 8<<10)
+	length, _ := file.ReadAt(data, offset)
+	data = data[:length]
+
+	start := bytes.Index(data, []byte("<<"))
+	end := bytes.Index(data, []byte("endobj"))
+	if start < 0 || end < start {
+		return nil, errInvalid
+	}
+
+	return readPDFDictionary(data[start:end]), nil
+}
+
+// pdfObjectOffset looks up the offset of the object with the number inside of the classic xref table, the startxref of the trailer points to
+func pdfObjectOffset(file *os.File, trailer []byte, number []byte) (int64, bool) {
+	matches := pdfStartXref.FindAllSubmatch(trailer, -1)
+	if len(matches) < 1 {
+		return 0, false
+	}
+
+	xrefOffset, err := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	objectNumber, err := strconv.ParseInt(string(number), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	table := make([]byte, 64<<10)
+	length, _ := file.ReadAt(table, xrefOffset)
+	table = table[:length]
+
+	if !bytes.HasPrefix(bytes.TrimSpace(table), []byte("xref")) {
+		// a cross reference stream is compressed, so we leave that to scanFor
+		return 0, false
+	}
+
+	lines := strings.Fields(string(table))[1:]
+
+	// the table consists of sections, which start with their first object number and the amount of entries
+	for len(lines) >= 2 {
+		first, errFirst := strconv.ParseInt(lines[0], 10, 64)
+		count, errCount := strconv.ParseInt(lines[1], 10, 64)
+		// check the numbers before calculating with them, so neither negative nor huge ones can point outside of the lines
+		if errFirst != nil || errCount != nil || first < 0 || count < 0 || count > int64(len(lines)) || int64(len(lines)) < 2+count*3 {
+			return 0, false
+		}
+
+		if objectNumber >= first && objectNumber < first+count {
+			entry := lines[2+(objectNumber-first)*3:]
+			offset, err := strconv.ParseInt(entry[0], 10, 64)
+			return offset, err == nil && entry[2] == "n"
+		}
+
+		lines = lines[2+count*3:]
+	}
+
+	return 0, false
+}
+
+// scanFor returns the offset of the first occurrence of the pattern in the first limit bytes of the file, that starts a line
+func scanFor(file *os.File, pattern []byte, limit int64) (int64, bool) {
+	chunk := make([]byte, 1<<20)
+	overlap := int64(len(pattern))
+
+	for offset := int64(0); offset < limit; offset += int64(len(chunk)) - overlap {
+		length, err := file.ReadAt(chunk, offset)
+
+		for searchStart := 0; searchStart < length; {
+			index := bytes.Index(chunk[searchStart:length], pattern)
+			if index < 0 {
+				break
+			}
+
+			index += searchStart
+			if (index == 0 && offset == 0) || (index > 0 && (chunk[index-1] == '\n' || chunk[index-1] == '\r')) {
+				return offset + int64(index), true
+			}
+
+			searchStart = index + 1
+		}
+
+		if err != nil {
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+// readPDFDictionary reads the title, author and subject strings from the content of a PDF dictionary
+func readPDFDictionary(dictionary []byte) map[string]string {
+	fields := map[string]string{}
+
+	for key, field := range pdfInfoKeys {
+		index := bytes.Index(dictionary, []byte(key))
+		if index < 0 {
+			continue
+		}
+
+		value := bytes.TrimLeft(dictionary[index+len(key):], " \t\r\n")
+		if len(value) < 1 {
+			continue
+		}
+
+		switch value[0] {
+		case '(':
+			fields[field] = decodePDFText(pdfLiteralString(value))
+		case '<':
+			fields[field] = decodePDFText(pdfHexString(value))
+		}
+	}
+
+	return fields
+}
+
+// pdfLiteralString decodes a string in parentheses, that starts at the beginning of data
+func pdfLiteralString(data []byte) []byte {
+	output := []byte{}
+	depth := 0
+
+	for index := 0; index < len(data); index++ {
+		char := data[index]
+
+		switch {
+		case char == '\\' && index+1 < len(data):
+			index++
+			switch escaped := data[index]; escaped {
+			case 'n':
+				output = append(output, '\n')
+			case 'r':
+				output = append(output, '\r')
+			case 't':
+				output = append(output, '\t')
+			case 'b':
+				output = append(output, '\b')
+			case 'f':
+				output = append(output, '\f')
+			case '\r', '\n':
+				// a line continuation
+			default:
+				// up to 3 octal digits
+				if escaped >= '0' && escaped <= '7' {
+					end := index
+					for end < len(data) && end < index+3 && data[end] >= '0' && data[end] <= '7' {
+						end++
+					}
+					value, _ := strconv.ParseUint(string(data[index:end]), 8, 8)
+					output = append(output, byte(value))
+					index = end - 1
+					continue
+				}
+				output = append(output, escaped)
+			}
+		case char == '(':
+			if depth > 0 {
+				output = append(output, char)
+			}
+			depth++
+		case char == ')':
+			depth--
+			if depth == 0 {
+				return output
+			}
+			output = append(output, char)
+		default:
+			output = append(output, char)
+		}
+	}
+
+	return output
+}
+
+// pdfHexString decodes a string in angle brackets, that starts at the beginning of data
+func pdfHexString(data []byte) []byte {
+	end := bytes.IndexByte(data, '>')
+	if end < 0 {
+		return nil
+	}
+
+	digits := []byte{}
+	for _, char := range data[1:end] {
+		if (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F') {
+			digits = append(digits, char)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	output := make([]byte, 0, len(digits)/2)
+	for index := 0; index < len(digits); index += 2 {
+		value, _ := strconv.ParseUint(string(digits[index:index+2]), 16, 8)
+		output = append(output, byte(value))
+	}
+
+	return output
+}
+
+// decodePDFText turns a PDF text string into a string, it's either UTF-16 with a byte order mark, UTF-8 with one, or PDFDocEncoding
+func decodePDFText(text []byte) string {
+	switch {
+	case bytes.HasPrefix(text, []byte{0xFE, 0xFF}) || bytes.HasPrefix(text, []byte{0xFF, 0xFE}):
+		return decodeUTF16(text, true)
+	case bytes.HasPrefix(text, []byte{0xEF, 0xBB, 0xBF}):
+		return string(text[3:])
+	default:
+		// PDFDocEncoding only differs from latin1 in characters, that don't appear in titles
+		return decodeLatin1(text)
+	}
+}
+
+// readOOXML reads the title, author and subject from the docProps/core.xml of a .docx, .xlsx or .pptx
+func readOOXML(filePath string) (map[string]string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var core dublinCore
+	if err := readZipXML(&reader.Reader, "docProps/core.xml", &core); err != nil {
+		return nil, err
+	}
+
+	return core.fields(), nil
+}
+
+// readEPUB reads the title, author and subject from the OPF file of an EPUB, the META-INF/container.xml points to
+func readEPUB(filePath string) (map[string]string, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := readZipXML(&reader.Reader, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) < 1 {
+		return nil, errInvalid
+	}
+
+	var opf struct {
+		Metadata dublinCore `xml:"metadata"`
+	}
+	if err := readZipXML(&reader.Reader, path.Clean(container.Rootfiles[0].FullPath), &opf); err != nil {
+		return nil, err
+	}
+
+	return opf.Metadata.fields(), nil
+}
+
+// readZipXML decodes the XML file with the name inside of the zip into target
+func readZipXML(reader *zip.Reader, name string, target any) error {
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+
+		if file.UncompressedSize64 > uint64(maxXMLSize) {
+			return errInvalid
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+
+		return xml.NewDecoder(io.LimitReader(content, maxXMLSize)).Decode(target)
+	}
+
+	return errInvalid
+}
+
+// fields returns the first title, the creators as the author and the subjects as the subject
+func (core dublinCore) fields() map[string]string {
+	fields := map[string]string{}
+
+	if len(core.Title) > 0 {
+		fields[FieldTitle] = core.Title[0]
+	}
+	fields[FieldAuthor] = strings.Join(core.Creators, ", ")
+	fields[FieldSubject] = strings.Join(core.Subjects, ", ")
+
+	return fields
+}
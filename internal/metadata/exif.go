@@ -0,0 +1,216 @@
+// Package metadata reads the metadata fields of media files and documents, like the date a photo was taken or the title of a PDF.
+package metadata
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	jpegReadLimit int64 = 256 << 10 // the EXIF data and the frame header are always at the start of a JPEG
+	tiffReadLimit int64 = 1 << 20   // the IFDs of a TIFF can be anywhere, but they're almost always at the start
+
+	tagImageWidth       uint16 = 0x0100
+	tagImageHeight      uint16 = 0x0101
+	tagMake             uint16 = 0x010F
+	tagModel            uint16 = 0x0110
+	tagDateTime         uint16 = 0x0132
+	tagExifIFD          uint16 = 0x8769
+	tagDateTimeOriginal uint16 = 0x9003
+	tagPixelXDimension  uint16 = 0xA002
+	tagPixelYDimension  uint16 = 0xA003
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// readJPEG reads the EXIF fields and the dimensions from the segments at the start of a JPEG
+func readJPEG(filePath string) (map[string]string, error) {
+	data, err := readStart(filePath, jpegReadLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errInvalid
+	}
+
+	fields := map[string]string{}
+
+	for index := 2; index+4 <= len(data); {
+		if data[index] != 0xFF {
+			return fields, nil
+		}
+
+		marker := data[index+1]
+		// markers without a length
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0xFF {
+			index++
+			continue
+		}
+
+		// the length includes its own 2 bytes, so anything shorter is corrupt
+		length := int(binary.BigEndian.Uint16(data[index+2:]))
+		if length < 2 {
+			return fields, errInvalid
+		}
+
+		segment := data[index+4 : min(index+2+length, len(data))]
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			readTIFFData(segment[6:], fields)
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC && len(segment) >= 5:
+			// the frame header holds the actual dimensions of the image
+			fields[FieldHeight] = strconv.Itoa(int(binary.BigEndian.Uint16(segment[1:])))
+			fields[FieldWidth] = strconv.Itoa(int(binary.BigEndian.Uint16(segment[3:])))
+		case marker == 0xDA || marker == 0xD9:
+			// the image data starts, so there are no more headers
+			return fields, nil
+		}
+
+		index += 2 + length
+	}
+
+	return fields, nil
+}
+
+// readTIFF reads the EXIF fields of a TIFF, which are the IFDs of the file itself
+func readTIFF(filePath string) (map[string]string, error) {
+	data, err := readStart(filePath, tiffReadLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	if !readTIFFData(data, fields) {
+		return nil, errInvalid
+	}
+
+	return fields, nil
+}
+
+// readTIFFData reads the fields from the IFD0 and the Exif IFD of the TIFF structure in data, it returns false if data isn't a TIFF structure
+func readTIFFData(data []byte, fields map[string]string) bool {
+	if len(data) < 8 {
+		return false
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return false
+	}
+
+	if order.Uint16(data[2:]) != 42 {
+		return false
+	}
+
+	ifd0 := readIFD(data, order, order.Uint32(data[4:]))
+
+	if value, ok := ifd0[tagMake]; ok {
+		fields[FieldCamera] = value
+	}
+	if value, ok := ifd0[tagModel]; ok {
+		// most models already start with the make
+		if !strings.HasPrefix(value, fields[FieldCamera]) {
+			value = fields[FieldCamera] + " " + value
+		}
+		fields[FieldCamera] = value
+	}
+	if value, ok := ifd0[tagDateTime]; ok {
+		fields[FieldTaken] = formatEXIFDate(value)
+	}
+	if value, ok := ifd0[tagImageWidth]; ok {
+		fields[FieldWidth] = value
+	}
+	if value, ok := ifd0[tagImageHeight]; ok {
+		fields[FieldHeight] = value
+	}
+
+	if offset, err := strconv.ParseUint(ifd0[tagExifIFD], 10, 32); err == nil {
+		exifIFD := readIFD(data, order, uint32(offset))
+
+		if value, ok := exifIFD[tagDateTimeOriginal]; ok {
+			fields[FieldTaken] = formatEXIFDate(value)
+		}
+		if value, ok := exifIFD[tagPixelXDimension]; ok {
+			fields[FieldWidth] = value
+		}
+		if value, ok := exifIFD[tagPixelYDimension]; ok {
+			fields[FieldHeight] = value
+		}
+	}
+
+	return true
+}
+
+// readIFD returns the ASCII, SHORT and LONG values of the IFD at the offset as strings by their tag
+func readIFD(data []byte, order binary.ByteOrder, offset uint32) map[uint16]string {
+	values := make(map[uint16]string)
+
+	if int64(offset)+2 > int64(len(data)) {
+		return values
+	}
+
+	count := int(order.Uint16(data[offset:]))
+
+	for index := range count {
+		start := int(offset) + 2 + index*12
+		if start+12 > len(data) {
+			break
+		}
+
+		tag := order.Uint16(data[start:])
+		valueType := order.Uint16(data[start+2:])
+		valueCount := order.Uint32(data[start+4:])
+
+		switch valueType {
+		case 2: // ASCII, stored inline if it fits into 4 bytes
+			var text []byte
+			if valueCount <= 4 {
+				text = data[start+8 : start+8+int(valueCount)]
+			} else if valueOffset := int64(order.Uint32(data[start+8:])); valueOffset+int64(valueCount) <= int64(len(data)) {
+				text = data[valueOffset : valueOffset+int64(valueCount)]
+			}
+			values[tag] = strings.TrimSpace(strings.TrimRight(string(text), "\x00"))
+		case 3: // SHORT
+			values[tag] = strconv.Itoa(int(order.Uint16(data[start+8:])))
+		case 4: // LONG
+			values[tag] = strconv.FormatUint(uint64(order.Uint32(data[start+8:])), 10)
+		}
+	}
+
+	return values
+}
+
+// formatEXIFDate turns an EXIF date ("2006:01:02 15:04:05") into "2006-01-02 15:04:05"
+func formatEXIFDate(date string) string {
+	if len(date) < 10 {
+		return date
+	}
+
+	return strings.ReplaceAll(date[:10], ":", "-") + date[10:]
+}
+
+// readStart reads up to limit bytes from the start of the file at filePath
+func readStart(filePath string, limit int64) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, limit))
+}
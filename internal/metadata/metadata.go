@@ -0,0 +1,145 @@
+// Package metadata reads the metadata fields of media files and documents, like the date a photo was taken or the title of a PDF.
+package metadata
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	FieldTaken   string = "taken"  // the date and time a photo was taken, formated as "2006-01-02 15:04:05"
+	FieldCamera  string = "camera" // the make and model of the camera
+	FieldWidth   string = "width"  // in pixels
+	FieldHeight  string = "height" // in pixels
+	FieldArtist  string = "artist"
+	FieldAlbum   string = "album"
+	FieldTitle   string = "title"
+	FieldYear    string = "year"
+	FieldAuthor  string = "author"
+	FieldSubject string = "subject"
+)
+
+// Fields are the names of all fields, that can be read by Read
+var Fields = []string{FieldTaken, FieldCamera, FieldWidth, FieldHeight, FieldArtist, FieldAlbum, FieldTitle, FieldYear, FieldAuthor, FieldSubject}
+
+// NameFields are the fields, that also get matched by the name search
+var NameFields = []string{FieldTitle, FieldAuthor, FieldSubject}
+
+// readers are the functions that read the fields of a file by its lower case extension
+var readers = map[string]func(filePath string) (map[string]string, error){
+	".jpg":  readJPEG,
+	".jpeg": readJPEG,
+	".tif":  readTIFF,
+	".tiff": readTIFF,
+	".mp3":  readMP3,
+	".flac": readFLAC,
+	".m4a":  readMP4,
+	".m4b":  readMP4,
+	".mp4":  readMP4,
+	".m4v":  readMP4,
+	".mov":  readMP4,
+	".pdf":  readPDF,
+	".docx": readOOXML,
+	".docm": readOOXML,
+	".xlsx": readOOXML,
+	".xlsm": readOOXML,
+	".pptx": readOOXML,
+	".pptm": readOOXML,
+	".epub": readEPUB,
+}
+
+var (
+	errInvalid error = errors.New("invalid metadata")
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Supported checks if the fields of files with the extension can be read
+func Supported(extension string) bool {
+	_, ok := readers[strings.ToLower(extension)]
+	return ok
+}
+
+// Extensions returns the extensions, that have a reader, sorted alphabetically
+func Extensions() []string {
+	extensions := []string{}
+	for extension := range readers {
+		extensions = append(extensions, extension)
+	}
+
+	sort.Strings(extensions)
+
+	return extensions
+}
+
+/*
+Read returns the metadata fields of the file at filePath, the parser gets picked by the extension.
+
+Fields that the file doesn't have are left out, so the map might be empty.
+*/
+func Read(filePath string) (map[string]string, error) {
+	reader, ok := readers[strings.ToLower(filepath.Ext(filePath))]
+	if !ok {
+		return map[string]string{}, nil
+	}
+
+	fields, err := reader(filePath)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	// empty values are the same as missing ones
+	for key, value := range fields {
+		value = strings.TrimSpace(strings.Trim(value, "\x00"))
+		if len(value) < 1 {
+			delete(fields, key)
+			continue
+		}
+
+		fields[key] = value
+	}
+
+	return fields, nil
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// decodeLatin1 turns ISO-8859-1 bytes into a string
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, 0, len(data))
+	for _, char := range data {
+		runes = append(runes, rune(char))
+	}
+
+	return string(runes)
+}
+
+// decodeUTF16 turns UTF-16 bytes into a string, a byte order mark overrides bigEndian
+func decodeUTF16(data []byte, bigEndian bool) string {
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFE && data[1] == 0xFF:
+			bigEndian, data = true, data[2:]
+		case data[0] == 0xFF && data[1] == 0xFE:
+			bigEndian, data = false, data[2:]
+		}
+	}
+
+	units := make([]uint16, 0, len(data)/2)
+	for index := 0; index+1 < len(data); index += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[index])<<8|uint16(data[index+1]))
+		} else {
+			units = append(units, uint16(data[index+1])<<8|uint16(data[index]))
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
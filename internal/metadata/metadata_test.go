@@ -0,0 +1,99 @@
+package metadata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// join concatenates the parts into the content of a test file
+func join(parts ...string) []byte {
+	return []byte(strings.Join(parts, ""))
+}
+
+// TestReadMalformed makes sure truncated and corrupt files are rejected or read partially, but never make a parser panic
+func TestReadMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+		invalid bool // Read has to return errInvalid, otherwise it has to succeed
+	}{
+		{name: "jpeg empty", file: "a.jpg", content: nil, invalid: true},
+		{name: "jpeg only start of image", file: "a.jpg", content: join("\xFF\xD8"), invalid: true},
+		{name: "jpeg segment length 0", file: "a.jpg", content: join("\xFF\xD8\xFF\xE0\x00\x00", "\x00\x00\x00\x00"), invalid: true},
+		{name: "jpeg segment length 1", file: "a.jpg", content: join("\xFF\xD8\xFF\xE0\x00\x01", "\x00\x00\x00\x00"), invalid: true},
+		{name: "jpeg segment past the end", file: "a.jpg", content: join("\xFF\xD8\xFF\xE1\xFF\xFF", "Exif\x00\x00II")},
+		{name: "jpeg exif with broken tiff", file: "a.jpg", content: join("\xFF\xD8\xFF\xE1\x00\x10", "Exif\x00\x00", "II\x2A\x00\xFF\xFF\xFF\xFF")},
+		{name: "jpeg short frame header", file: "a.jpg", content: join("\xFF\xD8\xFF\xC0\x00\x04\x08\x00")},
+		{name: "tiff empty", file: "a.tif", content: nil, invalid: true},
+		{name: "tiff unknown byte order", file: "a.tif", content: join("XX\x2A\x00\x08\x00\x00\x00"), invalid: true},
+		{name: "tiff wrong magic", file: "a.tif", content: join("II\x2B\x00\x08\x00\x00\x00"), invalid: true},
+		{name: "tiff ifd past the end", file: "a.tif", content: join("II\x2A\x00\xFF\xFF\xFF\x7F")},
+		{name: "tiff entries past the end", file: "a.tif", content: join("II\x2A\x00\x08\x00\x00\x00", "\xFF\xFF")},
+		{name: "tiff text past the end", file: "a.tif", content: join("II\x2A\x00\x08\x00\x00\x00", "\x01\x00", "\x0F\x01\x02\x00\x64\x00\x00\x00\xF0\xFF\xFF\xFF")},
+		{name: "tiff exif ifd past the end", file: "a.tif", content: join("II\x2A\x00\x08\x00\x00\x00", "\x01\x00", "\x69\x87\x04\x00\x01\x00\x00\x00\xF0\xFF\xFF\x0F")},
+		{name: "id3 empty", file: "a.mp3", content: nil},
+		{name: "id3 truncated header", file: "a.mp3", content: join("ID3\x03\x00")},
+		{name: "id3 tag past the end", file: "a.mp3", content: join("ID3\x03\x00\x00\x7F\x7F\x7F\x7F")},
+		{name: "id3 frame past the end", file: "a.mp3", content: join("ID3\x03\x00\x00\x00\x00\x00\x0E", "TIT2\x7F\xFF\xFF\xFF\x00\x00\x00title")},
+		{name: "id3 huge extended header", file: "a.mp3", content: join("ID3\x03\x00\x40\x00\x00\x00\x0A", "\xFF\xFF\xFF\xFF\x00\x00\x00\x00\x00\x00")},
+		{name: "id3 v2.2 frame past the end", file: "a.mp3", content: join("ID3\x02\x00\x00\x00\x00\x00\x08", "TT2\xFF\xFF\xFF\x00\x00")},
+		{name: "id3 empty text frame", file: "a.mp3", content: join("ID3\x03\x00\x00\x00\x00\x00\x0B", "TIT2\x00\x00\x00\x01\x00\x00\x00")},
+		{name: "id3v1 only", file: "a.mp3", content: join("TAG", strings.Repeat("\x00", 125))},
+		{name: "flac wrong magic", file: "a.flac", content: join("fLaX"), invalid: true},
+		{name: "flac truncated block header", file: "a.flac", content: join("fLaC\x04\x00")},
+		{name: "flac block past the end", file: "a.flac", content: join("fLaC\x84\x00\x10\x00", "\x05\x00\x00\x00")},
+		{name: "flac vendor past the end", file: "a.flac", content: join("fLaC\x84\x00\x00\x04", "\xFF\xFF\xFF\xFF")},
+		{name: "flac comment past the end", file: "a.flac", content: join("fLaC\x84\x00\x00\x10", "\x00\x00\x00\x00", "\xFF\xFF\xFF\x7F", "\xFF\xFF\xFF\x7F", "TIT")},
+		{name: "flac skipped block past the end", file: "a.flac", content: join("fLaC\x00\xFF\xFF\xFF")},
+		{name: "mp4 empty", file: "a.m4a", content: nil},
+		{name: "mp4 atom smaller than its header", file: "a.m4a", content: join("\x00\x00\x00\x04moov")},
+		{name: "mp4 atom past the end", file: "a.m4a", content: join("\x7F\xFF\xFF\xFFmoov")},
+		{name: "mp4 huge 64 bit size", file: "a.m4a", content: join("\x00\x00\x00\x01moov\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF")},
+		{name: "mp4 truncated 64 bit size", file: "a.m4a", content: join("\x00\x00\x00\x01moov\x00\x00")},
+		{name: "mp4 empty meta", file: "a.m4a", content: join("\x00\x00\x00\x18moov", "\x00\x00\x00\x10udta", "\x00\x00\x00\x08meta")},
+		{
+			name: "mp4 item smaller than its header",
+			file: "a.m4a",
+			content: join(
+				"\x00\x00\x00\x2Cmoov", "\x00\x00\x00\x24udta", "\x00\x00\x00\x1Cmeta\x00\x00\x00\x00",
+				"\x00\x00\x00\x10ilst", "\x00\x00\x00\x04\xA9nam",
+			),
+		},
+		{
+			name: "mp4 data atom past the item",
+			file: "a.m4a",
+			content: join(
+				"\x00\x00\x00\x44moov", "\x00\x00\x00\x3Cudta", "\x00\x00\x00\x34meta\x00\x00\x00\x00",
+				"\x00\x00\x00\x28ilst", "\x00\x00\x00\x20\xA9nam", "\x7F\xFF\xFF\xFFdata\x00\x00\x00\x01\x00\x00\x00\x00",
+			),
+		},
+		{name: "pdf negative xref count", file: "a.pdf", content: join("xref\n0 -5\n", "trailer << /Info 1 0 R >>\nstartxref\n0\n%%EOF")},
+		{name: "pdf huge xref count", file: "a.pdf", content: join("xref\n0 9223372036854775807\n", "trailer << /Info 1 0 R >>\nstartxref\n0\n%%EOF")},
+		{name: "pdf negative first object", file: "a.pdf", content: join("xref\n-3 1\n0000000000 65535 f\n", "trailer << /Info 1 0 R >>\nstartxref\n0\n%%EOF")},
+		{name: "pdf truncated xref entry", file: "a.pdf", content: join("xref\n1 1\n00000", "\ntrailer << /Info 1 0 R >>\nstartxref\n0\n%%EOF")},
+	}
+
+	dir := t.TempDir()
+
+	for index, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(dir, strings.Repeat("x", index+1)+test.file)
+			if err := os.WriteFile(filePath, test.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Read(filePath)
+
+			switch {
+			case test.invalid && !errors.Is(err, errInvalid):
+				t.Errorf("expected errInvalid, got %v", err)
+			case !test.invalid && err != nil:
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
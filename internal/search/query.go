@@ -0,0 +1,234 @@
+// Package search handles the search through the cache and the ranking of the results.
+package search
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/skillptm/bws/internal/cache"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	KindTerm       string = "kind"
+	MIMETerm       string = "mime"
+	ContentTerm    string = "content"
+	FolderSizeTerm string = "foldersize"
+)
+
+// termKeys are the keys of all terms, that can be used inside of a searchString with "key:value"
+var termKeys = map[string]bool{
+	KindTerm:       true,
+	MIMETerm:       true,
+	ContentTerm:    true,
+	FolderSizeTerm: true,
+}
+
+// sizeUnits are the factors of the units a size can be given in, they're all powers of 1024
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// sizeCondition is a parsed size term (e.g. ">10GB"), that a size has to fulfill
+type sizeCondition struct {
+	operator string
+	size     int64
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+parseQuery splits the terms (e.g. "kind:video") from the searchString and returns the remaining name and the values of the terms by their key.
+
+Only words with a known key are terms, so any other word with a ":" stays part of the name.
+The value of a term can be put in double quotes to contain spaces (e.g. artist:"pink floyd").
+If the searchString doesn't contain any terms, it's returned unchanged.
+*/
+func parseQuery(searchString string) (string, map[string][]string) {
+	terms := make(map[string][]string)
+	words := []string{}
+
+	for _, word := range splitWords(searchString) {
+		key, value, found := strings.Cut(word, ":")
+		key = strings.ToLower(key)
+		value = strings.Trim(value, "\"")
+
+		if !found || len(value) < 1 || !isTermKey(key) {
+			words = append(words, word)
+			continue
+		}
+
+		terms[key] = append(terms[key], value)
+	}
+
+	if len(terms) < 1 {
+		return searchString, terms
+	}
+
+	return strings.Join(words, " "), terms
+}
+
+// NamePart returns the part of the searchString, that gets matched against the names of the entries, in lower case (e.g. "holiday" for "Holiday kind:video")
+func NamePart(searchString string) string {
+	name, _ := parseQuery(searchString)
+
+	return strings.ToLower(name)
+}
+
+// isTermKey checks if the key belongs to a term, either a fixed one or a field of an extractor
+func isTermKey(key string) bool {
+	return termKeys[key] || cache.IsField(key)
+}
+
+// splitWords splits the searchString at every space, that isn't inside of double quotes
+func splitWords(searchString string) []string {
+	words := []string{}
+	quoted := false
+
+	var builder strings.Builder
+	for _, char := range searchString {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case unicode.IsSpace(char) && !quoted:
+			if builder.Len() > 0 {
+				words = append(words, builder.String())
+				builder.Reset()
+			}
+			continue
+		}
+
+		builder.WriteRune(char)
+	}
+
+	if builder.Len() > 0 {
+		words = append(words, builder.String())
+	}
+
+	return words
+}
+
+/*
+matchesFields checks if the fields contain every key of the terms with a value, that contains any of the values of that key (case insensitive).
+
+The values of the terms have to be in lower case.
+*/
+func matchesFields(fields map[string]string, terms map[string][]string) bool {
+	for key, values := range terms {
+		field, ok := fields[key]
+		if !ok {
+			return false
+		}
+
+		field = strings.ToLower(field)
+		if !slices.ContainsFunc(values, func(value string) bool { return strings.Contains(field, value) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+matchesMIME checks if the MIME type matches any of the patterns.
+
+A pattern can be a whole type ("image/png"), only the main type ("image" or "image/*") or a glob ("application/x-*").
+*/
+func matchesMIME(mimeType string, patterns []string) bool {
+	mimeType = strings.ToLower(mimeType)
+
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			pattern += "/*"
+		}
+
+		if matched, err := path.Match(pattern, mimeType); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+parseSizeCondition parses a size term like ">10GB", "<=500mb" or "=0" into a sizeCondition.
+
+The operator can be one of ">", ">=", "<", "<=" and "=", without one the size is the minimum (like ">=").
+The number may have a fraction and a unit (B, KB, MB, GB, TB, or only their first letter), which are all powers of 1024.
+*/
+func parseSizeCondition(value string) (sizeCondition, bool) {
+	condition := sizeCondition{operator: ">="}
+
+	for _, operator := range []string{">=", "<=", ">", "<", "="} {
+		if rest, found := strings.CutPrefix(value, operator); found {
+			condition.operator = operator
+			value = rest
+			break
+		}
+	}
+
+	value = strings.ToLower(strings.TrimSpace(value))
+	number := strings.TrimRightFunc(value, unicode.IsLetter)
+
+	unit, ok := sizeUnits[value[len(number):]]
+	if !ok {
+		return condition, false
+	}
+
+	size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || size < 0 {
+		return condition, false
+	}
+
+	condition.size = int64(size * float64(unit))
+
+	return condition, true
+}
+
+// matches checks if the size fulfills the condition
+func (condition sizeCondition) matches(size int64) bool {
+	switch condition.operator {
+	case ">":
+		return size > condition.size
+	case "<":
+		return size < condition.size
+	case "<=":
+		return size <= condition.size
+	case "=":
+		return size == condition.size
+	default:
+		return size >= condition.size
+	}
+}
+
+// matchesFolderSize checks if the size of the folder fulfills all the conditions, folders without a FolderUsage never match
+func matchesFolderSize(usage *cache.FolderUsage, conditions []sizeCondition) bool {
+	if usage == nil {
+		return false
+	}
+
+	for _, condition := range conditions {
+		if !condition.matches(usage.Size) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -4,10 +4,11 @@ package search
 import (
 	"io/fs"
 	"math"
-	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/skillptm/bws/internal/cache"
 	"github.com/skillptm/bws/internal/config"
 )
 
@@ -19,129 +20,155 @@ const (
 	minimumSizeModifier   int     = 25
 	timeSinceMaxModifier  float64 = 200
 	nameLengthMaxModifier float64 = 100
+	contentPhraseModifier int     = 150 // the content terms appear as they are, not only as separate words
+
+	fieldExactMatchModifier int     = 150 // the searchString is the whole title, author or subject
+	fieldLengthMaxModifier  float64 = 40
+
+	toRankChanSize int = 4096 // results that can wait to be ranked, before we wait for the workers
 )
 
-// RankedFile holds the points given to a file and it's full path
+// RankedFile holds the points given to a file, it's full path, the scope it was found in and if it's a link
 type RankedFile struct {
-	path   string
-	points int
+	Path    string
+	Points  int
+	Scope   string
+	IsLink  bool
+	Broken  bool
+	MIME    string
+	Snippet string // the line that matched the content terms
+	Archive string // the path of the archive the file is a member of
+	Fields  map[string]string
 }
 
-// newRankedFile constructs a RankedFile and ranks it based on: exact match, minimum file size,
-func newRankedFile(fileInfo fs.FileInfo, file []string, pattern *SearchString) *RankedFile {
-	newFile := RankedFile{path: file[0]}
+// newRankedFile constructs a RankedFile and ranks it based on: scope boost, exact match, minimum file size, time since modification and name length
+func newRankedFile(fileInfo fs.FileInfo, match *Match, pattern *SearchString) *RankedFile {
+	newFile := RankedFile{Path: match.Entry.Path, Scope: match.Scope, IsLink: match.Entry.IsLink, Broken: match.Entry.Broken, MIME: match.Entry.MIMEType(), Archive: match.Entry.Archive, Fields: match.Entry.Fields}
+
+	// add the boost of the scope the file was found in
+	if scope := config.BWSConfig.Scope(match.Scope); scope != nil {
+		newFile.Points += scope.Boost
+	}
+
+	// the name the searchString was matched against, with or without the extension or a metadata field like the title of a PDF
+	name := match.Entry.Name
+	exactModifier, lengthModifier := exactMatchModifier, nameLengthMaxModifier
+	if len(match.Field) > 0 {
+		// a matching title is a good hint, but weaker than the name the user gave the file
+		name = strings.ToLower(match.Entry.Fields[match.Field])
+		exactModifier, lengthModifier = fieldExactMatchModifier, fieldLengthMaxModifier
+	} else if !strings.Contains(name, pattern.name) {
+		name = match.Entry.FullName
+	}
 
 	// check if the searchString and the file name are an exact match (except for case)
-	if file[1] == pattern.name {
-		newFile.points += exactMatchModifier
+	if name == pattern.name {
+		newFile.Points += exactModifier
 	}
 
 	// check if the size is of a minimum file size
 	if fileInfo.Size() > minimumFileSize {
-		newFile.points += minimumSizeModifier
+		newFile.Points += minimumSizeModifier
 	}
 
 	timeSinceMod := time.Now().UTC().Unix() - fileInfo.ModTime().UTC().Unix()
 
 	// rank how long ago the file was last modified (longer ago = worse)
 	if timeSinceMod > fourYearsInSeconds {
-		newFile.points += 0
+		newFile.Points += 0
 	} else {
 
 		timeSinceReduction := 1 - math.Round(float64(timeSinceMod)/float64(fourYearsInSeconds)*math.Pow(10, 2))/math.Pow(10, 2)
 
-		newFile.points += int(timeSinceMaxModifier * timeSinceReduction)
+		newFile.Points += int(timeSinceMaxModifier * timeSinceReduction)
 	}
 
 	// rank how long the filename is compared to the searchString (longer = worse)
-	nameLengthReduction := math.Round(float64(pattern.length)/float64(len(file[1]))*math.Pow(10, 2)) / math.Pow(10, 2)
-	newFile.points += int(nameLengthMaxModifier * nameLengthReduction)
+	nameLengthReduction := math.Round(float64(pattern.length)/float64(len(name))*math.Pow(10, 2)) / math.Pow(10, 2)
+	newFile.Points += int(lengthModifier * nameLengthReduction)
+
+	// a file found by its content shows the line that matched
+	if len(pattern.contents) > 0 {
+		newFile.Snippet = cache.Snippet(match.Entry.Path, pattern.contents, config.BWSConfig.ContentMaxSize)
+		if len(newFile.Snippet) > 0 {
+			newFile.Points += contentPhraseModifier
+		}
+	}
 
 	return &newFile
 }
 
 // Rank ranks and sorts the results
-func Rank(searchResults *[][]string, pattern *SearchString, forceStopChan chan bool) *[]string {
-	output := []string{}
+func Rank(searchResults *[]Match, pattern *SearchString, forceStopChan chan bool) *[]RankedFile {
 	rankedFiles := []RankedFile{}
 
 	if len(*searchResults) < 1 {
-		return &output
+		return &rankedFiles
 	}
 
 	var wg sync.WaitGroup
 
-	// 10000000 is the channel size, because we just need a ridiculously large channel to store all the results
-	toRankChan := make(chan *[]string, 10000000)
-	rankedChan := make(chan *RankedFile, 10000000)
-	breakChan := make(chan bool, config.BWSConfig.CPUThreads*config.BWSConfig.CPUThreads)
-
-	// we add all the results in the channel before starting the goroutines to avoid race conditions
-	for _, file := range *searchResults {
-		toRankChan <- &file
-	}
+	toRankChan := make(chan *Match, toRankChanSize)
+	rankedChan := make(chan *RankedFile, toRankChanSize)
 
-	for range config.BWSConfig.CPUThreads {
+	for range max(config.BWSConfig.CPUThreads, 1) {
 		wg.Add(1)
-		go rankResults(toRankChan, rankedChan, breakChan, pattern, &wg, forceStopChan)
+		go rankResults(toRankChan, rankedChan, pattern, &wg, forceStopChan)
 	}
 
-	wg.Wait()
+	// feed the results to the workers, once all of them are in the workers stop on their own
+	go func() {
+		defer close(toRankChan)
 
-	close(toRankChan)
-	close(rankedChan)
-	close(breakChan)
+		for index := range *searchResults {
+			// check if we have to stop the baseSearch
+			if len(forceStopChan) > 0 {
+				return
+			}
 
-	// check if we have to stop the baseSearch
-	if len(forceStopChan) > 0 {
-		return &output
-	}
+			toRankChan <- &(*searchResults)[index]
+		}
+	}()
+
+	// once all workers are done, there is nothing more to collect
+	go func() {
+		wg.Wait()
+		close(rankedChan)
+	}()
 
 	for file := range rankedChan {
 		rankedFiles = append(rankedFiles, *file)
 	}
 
+	// check if we have to stop the baseSearch
+	if len(forceStopChan) > 0 {
+		return &rankedFiles
+	}
+
 	// sort the results
 	quickSort(rankedFiles)
 
-	// put the ranked and sorted paths onto the output
-	for _, file := range rankedFiles {
-		output = append(output, file.path)
-	}
-
-	return &output
+	return &rankedFiles
 }
 
 // rankResults takes the results from toRankChan, ranks them and inserts a pointer to them into rankedChan
-func rankResults(toRankChan <-chan *[]string, rankedChan chan<- *RankedFile, breakChan chan bool, pattern *SearchString, wg *sync.WaitGroup, forceStopChan chan bool) {
+func rankResults(toRankChan <-chan *Match, rankedChan chan<- *RankedFile, pattern *SearchString, wg *sync.WaitGroup, forceStopChan chan bool) {
 	defer wg.Done()
 
-	for {
-		select {
-		case file := <-toRankChan:
-			// check if we have to stop the baseSearch
-			if len(forceStopChan) > 0 {
-				return
-			}
-
-			fileInfo, err := os.Stat((*file)[0])
-			if err != nil {
-				// if we error it's most likely the file doesn't exist anymore, so we skip it
-				continue
-			}
-
-			rankedChan <- newRankedFile(fileInfo, *file, pattern)
-
-			if len(toRankChan) < 1 {
-				for range config.BWSConfig.CPUThreads {
-					breakChan <- true
-				}
-				return
-			}
+	for match := range toRankChan {
+		// check if we have to stop the baseSearch, we still drain the toRankChan so it can be closed
+		if len(forceStopChan) > 0 {
+			continue
+		}
 
-		case <-breakChan:
-			return
+		// a broken link gets ranked as the link itself and an archive member by its info from the archive
+		fileInfo, err := match.Entry.Info()
+		if err != nil {
+			// if we error it's most likely the file doesn't exist anymore, so we skip it
+			continue
 		}
+
+		rankedChan <- newRankedFile(fileInfo, match, pattern)
 	}
 }
 
@@ -152,18 +179,18 @@ func quickSort(rankedFiles []RankedFile) {
 	}
 
 	pivotIndex := len(rankedFiles) / 2
-	pivot := rankedFiles[pivotIndex].points
+	pivot := rankedFiles[pivotIndex].Points
 
 	// partition the slice into two halves
 	left := 0
 	right := len(rankedFiles) - 1
 
 	for left <= right {
-		for rankedFiles[left].points > pivot {
+		for rankedFiles[left].Points > pivot {
 			left++
 		}
 
-		for rankedFiles[right].points < pivot {
+		for rankedFiles[right].Points < pivot {
 			right--
 		}
 
@@ -9,103 +9,325 @@ import (
 	"github.com/skillptm/ssl/pkg/sslslices"
 
 	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+	"github.com/skillptm/bws/internal/metadata"
 )
 
 // <---------------------------------------------------------------------------------------------------->
 
 // SearchString holds all the data releated to the searchString input, so we only have to calculate them once
 type SearchString struct {
-	encoded    [8]byte
-	extensions []string
-	length     int
-	name       string
+	encoded      [8]byte
+	extensions   []string
+	filtered     bool // only entries with one of the extensions match, even if there are none
+	mimes        []string
+	contents     []string
+	fields       map[string][]string // the lower case values of the metadata terms by their field
+	folderSizes  []sizeCondition     // the conditions the size of all files below a folder has to fulfill
+	length       int
+	name         string
+	hasExtension bool
 }
 
-// NewSearchString returns a pointer to a SearchString struct based on the string input
+/*
+NewSearchString returns a pointer to a SearchString struct based on the string input.
+
+The fileExtensions may contain kinds (e.g. "video" or "kind:video"), which get replaced by their extensions.
+Kinds inside of the searchString (e.g. "holiday kind:video") additionally restrict the extensions, so only extensions in both are searched.
+MIME types inside of the searchString (e.g. "readme mime:text/x-python") restrict the results to files of those types.
+Content terms inside of the searchString (e.g. "config content:listen_port") restrict the results to text files, that contain all of them.
+Metadata terms inside of the searchString (e.g. "artist:queen taken:2023") restrict the results to media files with those fields.
+Folder size terms inside of the searchString (e.g. "foldersize:>10GB") restrict the results to folders, whose files together fulfill all of them.
+*/
 func NewSearchString(searchString string, fileExtensions []string) *SearchString {
-	// make sure all extensions begin with a period, unless it's a "File" or a "Folder"
-	for index, element := range fileExtensions {
+	searchString, terms := parseQuery(searchString)
+
+	extensions, filtered := formatExtensions(fileExtensions)
+
+	// every kind in the searchString is an alternative, but all of them together restrict the fileExtensions
+	if len(terms[KindTerm]) > 0 {
+		kindExtensions, _ := formatExtensions(prefixKinds(terms[KindTerm]))
+
+		if filtered {
+			bothExtensions := []string{}
+			for _, extension := range kindExtensions {
+				if sslslices.Contains(extensions, extension) {
+					bothExtensions = append(bothExtensions, extension)
+				}
+			}
+			kindExtensions = bothExtensions
+		}
+
+		extensions = kindExtensions
+		filtered = true
+	}
+
+	// only folders have a size of all the files below them, a size that can't be parsed doesn't match anything
+	folderSizes := []sizeCondition{}
+	if len(terms[FolderSizeTerm]) > 0 {
+		for _, value := range terms[FolderSizeTerm] {
+			condition, ok := parseSizeCondition(value)
+			if !ok {
+				condition = sizeCondition{operator: "<", size: 0}
+			}
+
+			folderSizes = append(folderSizes, condition)
+		}
+
+		if !filtered || sslslices.Contains(extensions, "Folder") {
+			extensions = []string{"Folder"}
+		} else {
+			extensions = []string{}
+		}
+		filtered = true
+	}
+
+	return &SearchString{
+		encoded:      cache.Encode(searchString),
+		extensions:   extensions,
+		filtered:     filtered,
+		mimes:        lowerAll(terms[MIMETerm]),
+		contents:     terms[ContentTerm],
+		fields:       fieldTerms(terms),
+		folderSizes:  folderSizes,
+		length:       len(searchString),
+		name:         strings.ToLower(searchString),
+		hasExtension: strings.Contains(searchString, "."),
+	}
+}
+
+/*
+formatExtensions makes sure all extensions are lower case and begin with a period, unless it's a "File" or a "Folder", and replaces kinds with their extensions.
+
+It also returns if the fileExtensions filter anything, which is the case even when they only consist of unknown kinds.
+*/
+func formatExtensions(fileExtensions []string) ([]string, bool) {
+	extensions := []string{}
+	filtered := false
+
+	for _, element := range fileExtensions {
+		element = strings.ToLower(element)
+
+		// skip empty strings
 		if len(element) < 1 {
-			// pop empty strings
-			fileExtensions = append(fileExtensions[:index], fileExtensions[index+1:]...)
 			continue
 		}
 
-		// ensure "File"/"Folder" have the right case
-		if element == "file" || element == "folder" {
-			fileExtensions[index] = "F" + element[1:]
+		filtered = true
+
+		// replace kinds with their extensions, unknown kinds don't match anything
+		name, isKind := strings.CutPrefix(element, KindTerm+":")
+		if kindExtensions, ok := config.BWSConfig.Kind(name); ok {
+			extensions = append(extensions, kindExtensions...)
+			continue
+		} else if isKind {
+			continue
+		}
+
+		switch {
+		case element == "file" || element == "folder":
+			// ensure "File"/"Folder" have the right case
 			element = "F" + element[1:]
+		case !strings.HasPrefix(element, "."):
+			element = "." + element
 		}
 
-		if !strings.HasPrefix(element, ".") && element != "File" && element != "Folder" {
-			fileExtensions[index] = "." + element
-			continue
+		extensions = append(extensions, element)
+	}
+
+	return extensions, filtered
+}
+
+// fieldTerms returns the lower case values of the terms, that are fields of an extractor
+func fieldTerms(terms map[string][]string) map[string][]string {
+	fields := make(map[string][]string)
+
+	for _, field := range cache.FieldNames() {
+		if values, ok := terms[field]; ok {
+			fields[field] = lowerAll(values)
 		}
 	}
 
-	return &SearchString{
-		encoded:    cache.Encode(searchString),
-		extensions: fileExtensions,
-		length:     len(searchString),
-		name:       strings.ToLower(searchString),
+	return fields
+}
+
+// lowerAll returns the values in lower case
+func lowerAll(values []string) []string {
+	lowered := []string{}
+
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+
+	return lowered
+}
+
+// prefixKinds adds "kind:" to the names, so they're never mistaken for extensions
+func prefixKinds(names []string) []string {
+	prefixed := []string{}
+
+	for _, name := range names {
+		prefixed = append(prefixed, KindTerm+":"+name)
 	}
+
+	return prefixed
+}
+
+// Match is a cached entry that matched the SearchString, together with the scope it was found in
+type Match struct {
+	Entry *cache.Entry
+	Scope string
+	Field string // the metadata field that matched, if the name itself didn't
 }
 
-// Start wraps around the walkFS function and returns all the results from the MainDirs and SecondaryDirs
-func Start(pattern *SearchString, extendedSearch bool, forceStopChan chan bool) (*[][]string, *SearchString) {
-	output := [][]string{}
+// Start wraps around the searchFS function and returns all the matches from the provided scopes of the fs
+func Start(fs *cache.Filesystem, pattern *SearchString, scopes []string, forceStopChan chan bool) (*[]Match, *SearchString) {
+	output := []Match{}
+
+	// the fs might still be generated, so we need to lock it while we read from it
+	fs.RLock()
+	defer fs.RUnlock()
 
-	// check the MainDirs for the search string
-	output = append(output, *pattern.searchFS(&cache.EntrieFilesystem.MainDirs, forceStopChan)...)
+	for _, scope := range scopes {
+		scopeCache, ok := fs.Scopes[scope]
+		if !ok {
+			continue
+		}
+
+		// content terms can only match in scopes, whose content is indexed
+		var contentMatches map[string]bool
+		if len(pattern.contents) > 0 {
+			if contentMatches, ok = scopeCache.ContentMatches(pattern.contents); !ok {
+				continue
+			}
+		}
 
-	// check the SecondaryDirs for the search string
-	if extendedSearch {
-		output = append(output, *pattern.searchFS(&cache.EntrieFilesystem.SecondaryDirs, forceStopChan)...)
+		// check the scope for the search string
+		for _, match := range *pattern.searchFS(scopeCache, contentMatches, forceStopChan) {
+			match.Scope = scope
+			output = append(output, match)
+		}
 	}
 
 	return &output, pattern
 }
 
-// searchFS searches one of the provided FileSystem maps, while skiping files for wrong extensions and ecoded values
-func (searchString *SearchString) searchFS(dirs *map[string]map[int][][]interface{}, forceStopChan chan bool) *[][]string {
-	output := [][]string{}
+/*
+searchFS searches the entries of a scope, while skiping files for wrong extensions and ecoded values.
+
+Entries whose name doesn't match, still match if one of their NameFields (e.g. the title of a PDF) does.
+If the searchString has content terms, only the entries inside of the contentMatches can match.
+*/
+func (searchString *SearchString) searchFS(scopeCache *cache.ScopeCache, contentMatches map[string]bool, forceStopChan chan bool) *[]Match {
+	output := []Match{}
 
 	// loop over the extensions
-	for extension, lengthMaps := range *dirs {
+	for extension, lengthMaps := range scopeCache.Entries {
 		// check if extensions were provided and if so, if the current extension is a provided one
-		if len(searchString.extensions) > 0 && !sslslices.Contains[string](searchString.extensions, extension) {
+		if !searchString.matchesExtension(extension) {
 			continue
 		}
 
+		// a searchString with a "." might include the extension, which isn't part of the length of the name
+		extensionLength := 0
+		if searchString.hasExtension && extension != "File" && extension != "Folder" {
+			extensionLength = len(extension)
+		}
+
 		// loop over the filename lengths
-		for length, fileSlices := range lengthMaps {
-			// check if the filename is longer than the searchString
-			if length < searchString.length {
+		for length, entries := range lengthMaps {
+			// check if the filename is longer than the searchString, if it isn't only the metadata fields could still match
+			nameFits := length+extensionLength >= searchString.length
+			if !nameFits && !scopeCache.FieldExtensions[extension] {
 				continue
 			}
 
 			// loop over the actual files
-			for _, file := range fileSlices {
+			for _, entry := range entries {
 				// check if we have to stop the baseSearch
 				if len(forceStopChan) > 0 {
 					return &output
 				}
 
-				// check if all required letters are inside the filename
-				if !cache.CompareBytes(searchString.encoded, file[2].([8]byte)) {
+				field := ""
+				if !nameFits || !searchString.matchesName(entry) {
+					if field = searchString.matchingNameField(entry); len(field) < 1 {
+						continue
+					}
+				}
+
+				// check if the file contains the content terms
+				if len(searchString.contents) > 0 && !contentMatches[entry.Path] {
 					continue
 				}
 
-				// do a substring search over the filename
-				if !strings.Contains(file[1].(string), searchString.name) {
+				// check if the metadata fields match
+				if len(searchString.fields) > 0 && !matchesFields(entry.Fields, searchString.fields) {
 					continue
 				}
 
-				// if the searchString is inside the filename add it's path and name to the output
-				output = append(output, []string{file[0].(string), file[1].(string)})
+				// check if the size of the files below the folder fits
+				if len(searchString.folderSizes) > 0 && !matchesFolderSize(scopeCache.Folders[entry.Path], searchString.folderSizes) {
+					continue
+				}
+
+				// check if the MIME type is one of the provided ones
+				if len(searchString.mimes) > 0 && !matchesMIME(entry.MIMEType(), searchString.mimes) {
+					continue
+				}
+
+				// if the searchString is inside the filename add the entry to the output
+				output = append(output, Match{Entry: entry, Field: field})
 			}
 		}
 	}
 
 	return &output
 }
+
+/*
+matchesExtension checks if the entries with the extension get searched, which is always the case without a filter.
+
+A compound extension (e.g. ".tar.gz") also matches the extensions it ends with (e.g. ".gz"), so a filter keeps finding these files.
+*/
+func (searchString *SearchString) matchesExtension(extension string) bool {
+	if !searchString.filtered {
+		return true
+	}
+
+	compound := strings.Count(extension, ".") > 1
+
+	for _, filter := range searchString.extensions {
+		if extension == filter || (compound && strings.HasPrefix(filter, ".") && strings.HasSuffix(extension, filter)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesName checks if the searchString is inside the name of the entry
+func (searchString *SearchString) matchesName(entry *cache.Entry) bool {
+	// check if all required letters are inside the filename
+	if !cache.CompareBytes(searchString.encoded, entry.Encoded) {
+		return false
+	}
+
+	// do a substring search over the filename, if the searchString has a "." it may also match the name with its extension
+	return strings.Contains(entry.Name, searchString.name) || (searchString.hasExtension && strings.Contains(entry.FullName, searchString.name))
+}
+
+// matchingNameField returns the first of the NameFields of the entry, that contains the searchString, or an empty string if none does
+func (searchString *SearchString) matchingNameField(entry *cache.Entry) string {
+	if entry.Fields == nil || searchString.length < 1 {
+		return ""
+	}
+
+	for _, field := range metadata.NameFields {
+		if value, ok := entry.Fields[field]; ok && strings.Contains(strings.ToLower(value), searchString.name) {
+			return field
+		}
+	}
+
+	return ""
+}
@@ -19,7 +19,8 @@ func InsertUsername(pathInputs []string) ([]string, error) {
 		return pathInputs, fmt.Errorf("couldn't get current user for username; %s", err.Error())
 	}
 
-	username := strings.Split(currentUser.Username, "\\")[1]
+	// on windows the username is prefixed with the domain ("DOMAIN\username"), on other systems it isn't
+	username := currentUser.Username[strings.LastIndex(currentUser.Username, "\\")+1:]
 
 	for index, path := range pathInputs {
 		pathInputs[index] = strings.ReplaceAll(path, "<USERNAME>", username)
@@ -0,0 +1,305 @@
+// Package client mirrors the search functions of bws, but runs them on the bws daemon, if one is running.
+package client
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/skillptm/bws"
+	"github.com/skillptm/bws/internal/daemon"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	dialTimeout time.Duration = 200 * time.Millisecond // how long we try to reach the daemon, before we fall back to the own cache
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+var (
+	socketMutex sync.RWMutex
+	socketPath  string = daemon.SocketPath()
+
+	errStopped error = errors.New("the request was stopped")
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Query is a search for SearchUntil. If Scopes are provided they get searched, otherwise Extended decides
+type Query struct {
+	SearchString   string
+	FileExtensions []string
+	Extended       bool
+	Scopes         []string
+	Limit          int // the results get cut to this many, 0 keeps all of them
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+SetSocketPath allows you to set the path of the socket the daemon listens on.
+
+By default this value is the $BWS_SOCKET, or "bws-<uid>.sock" inside of $XDG_RUNTIME_DIR or the temp folder (the named pipe "\\.\pipe\bws-<sid>" on Windows).
+*/
+func SetSocketPath(path string) {
+	socketMutex.Lock()
+	defer socketMutex.Unlock()
+
+	socketPath = path
+}
+
+// Running checks if a daemon is listening on the socket
+func Running() bool {
+	conn, err := dial()
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+
+	return true
+}
+
+// dial connects to the socket of the daemon
+func dial() (net.Conn, error) {
+	socketMutex.RLock()
+	defer socketMutex.RUnlock()
+
+	return daemon.Dial(socketPath, dialTimeout)
+}
+
+/*
+call sends the request to the daemon and returns its reply.
+
+If something is sent into the breakChan before the reply arrived, the connection gets closed, which stops the request on the daemon,
+and errStopped is returned. Any other error means the daemon couldn't be reached or answer.
+*/
+func call(request daemon.Request, breakChan chan bool) (*daemon.Reply, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-breakChan:
+			close(stopped)
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+
+	reply := daemon.Reply{}
+	err = json.NewDecoder(conn).Decode(&reply)
+
+	select {
+	case <-stopped:
+		return nil, errStopped
+	default:
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reply.Error) > 0 {
+		return &reply, errors.New(reply.Error)
+	}
+
+	return &reply, nil
+}
+
+/*
+remoteSearch runs the search on the daemon and returns its Response, if it was stopped early and if the daemon could be reached.
+
+The Ready channel of an Incomplete Response gets closed, once the cache of the daemon is complete.
+*/
+func remoteSearch(params daemon.SearchParams, breakChan chan bool) (*bws.Response, bool, bool) {
+	reply, err := call(daemon.Request{Method: daemon.MethodSearch, Search: &params}, breakChan)
+	if errors.Is(err, errStopped) {
+		return &bws.Response{Results: []bws.Result{}, Ready: closedChan()}, true, true
+	} else if err != nil || reply.Response == nil {
+		return nil, false, false
+	}
+
+	response := reply.Response
+	if response.Results == nil {
+		response.Results = []bws.Result{}
+	}
+
+	if !response.Incomplete {
+		response.Ready = closedChan()
+		return response, false, true
+	}
+
+	ready := make(chan struct{})
+	response.Ready = ready
+
+	go func() {
+		defer close(ready)
+		call(daemon.Request{Method: daemon.MethodReady}, make(chan bool, 1))
+	}()
+
+	return response, false, true
+}
+
+// closedChan returns a channel, that is already closed
+func closedChan() <-chan struct{} {
+	closed := make(chan struct{})
+	close(closed)
+
+	return closed
+}
+
+/*
+Search behaves exactly like bws.Search, but runs on the daemon if it's running.
+
+If the daemon can't be reached, the search runs on the cache of this process instead, exactly as bws.Search would.
+*/
+func Search(searchString string, fileExtensions []string, extendedSearch bool) []string {
+	return DetailedSearch(searchString, fileExtensions, extendedSearch).Paths()
+}
+
+// GoSearchWithBreak behaves exactly like bws.GoSearchWithBreak, but runs on the daemon if it's running.
+func GoSearchWithBreak(searchString string, fileExtensions []string, extendedSearch bool, breakChan chan bool) ([]string, bool) {
+	response, brokenEarly := GoDetailedSearchWithBreak(searchString, fileExtensions, extendedSearch, breakChan)
+	return response.Paths(), brokenEarly
+}
+
+// DetailedSearch behaves exactly like bws.DetailedSearch, but runs on the daemon if it's running.
+func DetailedSearch(searchString string, fileExtensions []string, extendedSearch bool) *bws.Response {
+	response, _ := GoDetailedSearchWithBreak(searchString, fileExtensions, extendedSearch, make(chan bool, 1)) // insert a dummy channel, as it's not needed here
+	return response
+}
+
+// GoDetailedSearchWithBreak behaves exactly like bws.GoDetailedSearchWithBreak, but runs on the daemon if it's running.
+func GoDetailedSearchWithBreak(searchString string, fileExtensions []string, extendedSearch bool, breakChan chan bool) (*bws.Response, bool) {
+	params := daemon.SearchParams{SearchString: searchString, FileExtensions: fileExtensions, Extended: extendedSearch}
+
+	if response, brokenEarly, ok := remoteSearch(params, breakChan); ok {
+		return response, brokenEarly
+	}
+
+	return bws.GoDetailedSearchWithBreak(searchString, fileExtensions, extendedSearch, breakChan)
+}
+
+// SearchScopes behaves exactly like bws.SearchScopes, but runs on the daemon if it's running.
+func SearchScopes(searchString string, fileExtensions []string, scopes []string) *bws.Response {
+	response, _ := GoSearchScopesWithBreak(searchString, fileExtensions, scopes, make(chan bool, 1)) // insert a dummy channel, as it's not needed here
+	return response
+}
+
+// GoSearchScopesWithBreak behaves exactly like bws.GoSearchScopesWithBreak, but runs on the daemon if it's running.
+func GoSearchScopesWithBreak(searchString string, fileExtensions []string, scopes []string, breakChan chan bool) (*bws.Response, bool) {
+	params := daemon.SearchParams{SearchString: searchString, FileExtensions: fileExtensions, Scopes: scopes, UseScopes: true}
+
+	if response, brokenEarly, ok := remoteSearch(params, breakChan); ok {
+		return response, brokenEarly
+	}
+
+	return bws.GoSearchScopesWithBreak(searchString, fileExtensions, scopes, breakChan)
+}
+
+/*
+SearchUntil runs the query on the daemon if it's running, otherwise on the own cache, and breaks it once cancelled gets closed.
+It returns the Response, with the results cut to the Limit of the query, and if the search was broken early.
+
+The breakChan of the search gets closed afterwards, so nothing keeps listening on it, once the search is done.
+*/
+func SearchUntil(query Query, cancelled <-chan struct{}) (*bws.Response, bool) {
+	breakChan := make(chan bool, 1)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		select {
+		case <-cancelled:
+			breakChan <- true
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		close(done)
+		<-exited
+		close(breakChan)
+	}()
+
+	var response *bws.Response
+	var brokenEarly bool
+	if len(query.Scopes) > 0 {
+		response, brokenEarly = GoSearchScopesWithBreak(query.SearchString, query.FileExtensions, query.Scopes, breakChan)
+	} else {
+		response, brokenEarly = GoDetailedSearchWithBreak(query.SearchString, query.FileExtensions, query.Extended, breakChan)
+	}
+
+	if query.Limit > 0 && len(response.Results) > query.Limit {
+		response.Results = response.Results[:query.Limit]
+	}
+
+	return response, brokenEarly
+}
+
+// GetIndexStatus behaves exactly like bws.GetIndexStatus, but returns the IndexStatus of the daemon if it's running.
+func GetIndexStatus() bws.IndexStatus {
+	if reply, err := call(daemon.Request{Method: daemon.MethodStatus}, make(chan bool, 1)); err == nil && reply.Status != nil {
+		return *reply.Status
+	}
+
+	return bws.GetIndexStatus()
+}
+
+// Stats behaves exactly like bws.Stats, but returns the ScopeStats of the daemon if it's running.
+func Stats() []bws.ScopeStats {
+	if reply, err := call(daemon.Request{Method: daemon.MethodStats}, make(chan bool, 1)); err == nil {
+		return nonNil(reply.Stats)
+	}
+
+	return bws.Stats()
+}
+
+// ForceUpdateCache behaves exactly like bws.ForceUpdateCache, but regenerates the cache of the daemon if it's running.
+func ForceUpdateCache() {
+	if _, err := call(daemon.Request{Method: daemon.MethodBuild}, make(chan bool, 1)); err == nil {
+		return
+	}
+
+	bws.ForceUpdateCache()
+}
+
+// UpdateScopes behaves exactly like bws.UpdateScopes, but updates the scopes of the daemon if it's running.
+func UpdateScopes(scopes []string) error {
+	reply, err := call(daemon.Request{Method: daemon.MethodUpdate, Scopes: scopes}, make(chan bool, 1))
+	if reply != nil {
+		// the daemon answered, so any error is the one of the update itself
+		return err
+	}
+
+	return bws.UpdateScopes(scopes)
+}
+
+// nonNil returns the stats, or an empty slice if they're nil
+func nonNil(stats []bws.ScopeStats) []bws.ScopeStats {
+	if stats == nil {
+		return []bws.ScopeStats{}
+	}
+
+	return stats
+}
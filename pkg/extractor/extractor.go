@@ -0,0 +1,89 @@
+// Package extractor allows you to register your own extractors, that read searchable fields from files during the cache generation.
+package extractor
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Extractor reads key/value fields from files during the cache generation.
+
+Fields returns the keys the Extractor sets, they have to be lower case and may not contain ":", spaces or double quotes.
+Handles returns which files get passed to Extract, by their extension, MIME type or a predicate.
+Extract gets called for every new or changed file the Handles match, with a context that gets cancelled after the extractor timeout.
+If Extract returns an error, the file has no fields from this Extractor.
+
+The fields can be searched with "key:value" terms inside of the searchString (e.g. "project:apollo") and are part of the Results of bws.DetailedSearch.
+*/
+type Extractor = cache.Extractor
+
+/*
+Handles describes, which files an Extractor reads. A file is handled, if any of them match.
+
+Extensions are matched case insensitive with a leading "." (e.g. ".proj"). MIMEs may be whole types ("image/png"),
+only main types ("image") or globs ("application/x-*"), they use the sniffed MIME type if options.SetSniffMIME is turned on and the one guessed from the extension otherwise.
+*/
+type Handles = cache.Handles
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Register adds the extractor under the name or replaces an existing one with that name.
+The name "metadata" belongs to the built-in extractor, which gets turned on with options.SetExtractMetadata.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+*/
+func Register(name string, extractor Extractor) error {
+	if err := cache.RegisterExtractor(name, extractor); err != nil {
+		return err
+	}
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+Remove removes the extractor with the name.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+*/
+func Remove(name string) error {
+	if !cache.RemoveExtractor(name) {
+		return fmt.Errorf("there is no extractor called %s", name)
+	}
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetLimits allows you to set how many files may be read by the extractors at once and how long they may take for a single file.
+A timeout of 0 means there is no limit.
+
+By default the limits are 4 files at once and 5 seconds.
+*/
+func SetLimits(concurrency int, timeout time.Duration) error {
+	if concurrency < 1 {
+		return errors.New("the extractor concurrency has to be at least 1")
+	}
+
+	if timeout < 0 {
+		return errors.New("the extractor timeout can't be negative")
+	}
+
+	config.BWSConfig.ExtractorConcurrency = concurrency
+	config.BWSConfig.ExtractorTimeout = timeout
+
+	return nil
+}
@@ -0,0 +1,324 @@
+// Package options allows you to set values from the configaration of the cache generation and search.
+package options
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/skillptm/bws/internal/config"
+	"github.com/skillptm/bws/pkg/extractor"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Duration is a time.Duration, that is written as a string like "10s" or "3m0s" inside of a File
+type Duration time.Duration
+
+// FileScope is a scope inside of a File, the roots and excludes of the presets "main" and "secondary" are ignored
+type FileScope struct {
+	Name     string    `json:"name"`
+	Roots    []string  `json:"roots"`
+	Excludes []string  `json:"excludes"`
+	Extended bool      `json:"extended"`
+	Refresh  *Duration `json:"refresh"`
+	Boost    *int      `json:"boost"`
+}
+
+/*
+File is the config in the form of a JSON file, with the same keys as the default config.
+
+Every key that is left out of a File keeps its current value when the File gets applied, so a File only needs to hold what it changes.
+*/
+type File struct {
+	CPUThreads           *int                `json:"cpuThreads"`
+	MainDirs             []string            `json:"mainDirs"`
+	ExcludeSubMainDirs   []string            `json:"excludeSubMainDirs"`
+	SecondaryDirs        []string            `json:"secondaryDirs"`
+	ExcludeDirs          []string            `json:"excludeDirs"`
+	ExcludeDirsByName    []string            `json:"excludeDirsByName"`
+	ExcludePatterns      []string            `json:"excludePatterns"`
+	UseIgnoreFiles       *bool               `json:"useIgnoreFiles"`
+	IgnoreFiles          []string            `json:"ignoreFiles"`
+	Symlinks             *string             `json:"symlinks"`
+	OneFilesystem        *bool               `json:"oneFilesystem"`
+	ExcludeFSTypes       []string            `json:"excludeFSTypes"`
+	ReadTimeout          *Duration           `json:"readTimeout"`
+	CompoundExtensions   []string            `json:"compoundExtensions"`
+	Kinds                map[string][]string `json:"kinds"`
+	SniffMIME            *bool               `json:"sniffMIME"`
+	SniffRate            *int                `json:"sniffRate"`
+	IndexContent         *bool               `json:"indexContent"`
+	ContentMaxSize       *int64              `json:"contentMaxSize"`
+	IndexArchives        *bool               `json:"indexArchives"`
+	ArchiveMaxSize       *int64              `json:"archiveMaxSize"`
+	ArchiveMaxMembers    *int                `json:"archiveMaxMembers"`
+	ExtractMetadata      *bool               `json:"extractMetadata"`
+	ExtractorConcurrency *int                `json:"extractorConcurrency"`
+	ExtractorTimeout     *Duration           `json:"extractorTimeout"`
+	Scopes               []FileScope         `json:"scopes"`
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// MarshalJSON writes the duration as a string like "10s"
+func (duration Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(duration).String())
+}
+
+// UnmarshalJSON reads the duration from a string like "10s" or "1h30m"
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("a duration has to be a string like \"10s\"; %s", err.Error())
+	}
+
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+
+	*duration = Duration(parsed)
+
+	return nil
+}
+
+/*
+ReadFile reads a File from the JSON at filePath.
+
+Unknown keys are an error, so a typo doesn't silently leave a setting at its default.
+*/
+func ReadFile(filePath string) (*File, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the config file; %s", err.Error())
+	}
+
+	return ParseFile(data)
+}
+
+// ParseFile reads a File from the JSON inside of data, unknown keys are an error
+func ParseFile(data []byte) (*File, error) {
+	file := File{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("couldn't parse the config file; %s", err.Error())
+	}
+
+	return &file, nil
+}
+
+/*
+CurrentFile returns the current config as a File, with every key set.
+
+Only the refresh and boost of the presets "main" and "secondary" are part of its Scopes, as their roots come from the MainDirs and SecondaryDirs.
+*/
+func CurrentFile() *File {
+	current := config.BWSConfig
+
+	file := File{
+		CPUThreads:           &current.CPUThreads,
+		MainDirs:             current.MainDirs,
+		ExcludeSubMainDirs:   current.ExcludeSubMainDirs,
+		SecondaryDirs:        current.SecondaryDirs,
+		ExcludeDirs:          current.ExcludeDirs,
+		ExcludeDirsByName:    current.ExcludeDirsByName,
+		ExcludePatterns:      current.ExcludePatterns,
+		UseIgnoreFiles:       &current.UseIgnoreFiles,
+		IgnoreFiles:          current.IgnoreFiles,
+		Symlinks:             &current.Symlinks,
+		OneFilesystem:        &current.OneFilesystem,
+		ExcludeFSTypes:       current.ExcludeFSTypes,
+		ReadTimeout:          (*Duration)(&current.ReadTimeout),
+		CompoundExtensions:   current.CompoundExtensions,
+		Kinds:                current.Kinds,
+		SniffMIME:            &current.SniffMIME,
+		SniffRate:            &current.SniffRate,
+		IndexContent:         &current.IndexContent,
+		ContentMaxSize:       &current.ContentMaxSize,
+		IndexArchives:        &current.IndexArchives,
+		ArchiveMaxSize:       &current.ArchiveMaxSize,
+		ArchiveMaxMembers:    &current.ArchiveMaxMembers,
+		ExtractMetadata:      &current.ExtractMetadata,
+		ExtractorConcurrency: &current.ExtractorConcurrency,
+		ExtractorTimeout:     (*Duration)(&current.ExtractorTimeout),
+		Scopes:               []FileScope{},
+	}
+
+	for _, scope := range current.Scopes {
+		fileScope := FileScope{Name: scope.Name, Refresh: (*Duration)(&scope.Refresh), Boost: &scope.Boost, Extended: scope.Extended}
+
+		if scope.Name != config.MainScope && scope.Name != config.SecondaryScope {
+			fileScope.Roots = scope.Roots
+			fileScope.Excludes = scope.Excludes
+		}
+
+		file.Scopes = append(file.Scopes, fileScope)
+	}
+
+	return &file
+}
+
+/*
+Apply sets every key of the file with the matching set function of this package.
+
+It doesn't stop at the first invalid key, but applies all valid ones and returns the errors of all invalid ones together,
+each prefixed with its key. Using this function will cause the cache to regenerate before the next bws.Search execution.
+*/
+func (file *File) Apply() error {
+	errs := []error{}
+
+	// check adds the err with the key it belongs to, if there is one
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", key, err.Error()))
+		}
+	}
+
+	if file.CPUThreads != nil {
+		check("cpuThreads", SetCPUThreads(*file.CPUThreads))
+	}
+
+	if file.MainDirs != nil {
+		check("mainDirs", SetMainDirs(file.MainDirs))
+	}
+
+	if file.ExcludeSubMainDirs != nil {
+		check("excludeSubMainDirs", SetExcludeSubMainDirs(file.ExcludeSubMainDirs))
+	}
+
+	if file.SecondaryDirs != nil {
+		check("secondaryDirs", SetSecondaryDirs(file.SecondaryDirs))
+	}
+
+	if file.ExcludeDirs != nil {
+		check("excludeDirs", SetExcludeDirs(file.ExcludeDirs))
+	}
+
+	if file.ExcludeDirsByName != nil {
+		SetExcludeDirsByName(file.ExcludeDirsByName)
+	}
+
+	if file.ExcludePatterns != nil {
+		check("excludePatterns", SetExcludePatterns(file.ExcludePatterns))
+	}
+
+	if file.UseIgnoreFiles != nil {
+		SetUseIgnoreFiles(*file.UseIgnoreFiles)
+	}
+
+	if file.IgnoreFiles != nil {
+		check("ignoreFiles", SetIgnoreFiles(file.IgnoreFiles))
+	}
+
+	if file.Symlinks != nil {
+		check("symlinks", SetSymlinks(*file.Symlinks))
+	}
+
+	if file.OneFilesystem != nil {
+		SetOneFilesystem(*file.OneFilesystem)
+	}
+
+	if file.ExcludeFSTypes != nil {
+		check("excludeFSTypes", SetExcludeFSTypes(file.ExcludeFSTypes))
+	}
+
+	if file.ReadTimeout != nil {
+		check("readTimeout", SetReadTimeout(time.Duration(*file.ReadTimeout)))
+	}
+
+	if file.CompoundExtensions != nil {
+		check("compoundExtensions", SetCompoundExtensions(file.CompoundExtensions))
+	}
+
+	kindNames := []string{}
+	for name := range file.Kinds {
+		kindNames = append(kindNames, name)
+	}
+	sort.Strings(kindNames)
+
+	for _, name := range kindNames {
+		check(fmt.Sprintf("kinds[%s]", name), SetKind(name, file.Kinds[name]))
+	}
+
+	if file.SniffMIME != nil {
+		SetSniffMIME(*file.SniffMIME)
+	}
+
+	if file.SniffRate != nil {
+		check("sniffRate", SetSniffRate(*file.SniffRate))
+	}
+
+	if file.IndexContent != nil {
+		SetIndexContent(*file.IndexContent)
+	}
+
+	if file.ContentMaxSize != nil {
+		check("contentMaxSize", SetContentMaxSize(*file.ContentMaxSize))
+	}
+
+	if file.IndexArchives != nil {
+		SetIndexArchives(*file.IndexArchives)
+	}
+
+	// the archive limits are set together, so a missing one keeps its current value
+	if file.ArchiveMaxSize != nil || file.ArchiveMaxMembers != nil {
+		maxSize, maxMembers := config.BWSConfig.ArchiveMaxSize, config.BWSConfig.ArchiveMaxMembers
+		if file.ArchiveMaxSize != nil {
+			maxSize = *file.ArchiveMaxSize
+		}
+		if file.ArchiveMaxMembers != nil {
+			maxMembers = *file.ArchiveMaxMembers
+		}
+
+		check("archiveMaxSize/archiveMaxMembers", SetArchiveLimits(maxSize, maxMembers))
+	}
+
+	if file.ExtractMetadata != nil {
+		SetExtractMetadata(*file.ExtractMetadata)
+	}
+
+	// the extractor limits are set together as well
+	if file.ExtractorConcurrency != nil || file.ExtractorTimeout != nil {
+		concurrency, timeout := config.BWSConfig.ExtractorConcurrency, config.BWSConfig.ExtractorTimeout
+		if file.ExtractorConcurrency != nil {
+			concurrency = *file.ExtractorConcurrency
+		}
+		if file.ExtractorTimeout != nil {
+			timeout = time.Duration(*file.ExtractorTimeout)
+		}
+
+		check("extractorConcurrency/extractorTimeout", extractor.SetLimits(concurrency, timeout))
+	}
+
+	for _, scope := range file.Scopes {
+		key := fmt.Sprintf("scopes[%s]", scope.Name)
+
+		// the presets already exist, only their refresh and boost can be changed
+		if scope.Name != config.MainScope && scope.Name != config.SecondaryScope {
+			if err := SetScope(scope.Name, scope.Roots, scope.Excludes, scope.Extended); err != nil {
+				check(key, err)
+				continue
+			}
+		}
+
+		if scope.Refresh != nil {
+			check(key, SetScopeRefresh(scope.Name, time.Duration(*scope.Refresh)))
+		}
+
+		if scope.Boost != nil {
+			check(key, SetScopeBoost(scope.Name, *scope.Boost))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+// Package options allows you to set values from the configaration of the cache generation and search.
+package options
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skillptm/bws/internal/config"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+SetKind adds a kind to the config or replaces the extensions of an existing one.
+A kind is a category of file types, that can be used in a search with "kind:<name>" inside of the searchString or as one of the fileExtensions.
+
+The extensions are case insensitive and the leading "." is optional. Kind names are case insensitive too.
+
+By default these kinds exist: "video", "image", "audio", "document", "archive", "code" and "executable".
+*/
+func SetKind(name string, extensions []string) error {
+	if len(name) < 1 || strings.ContainsAny(name, ": ") {
+		return fmt.Errorf("'%s' isn't a valid kind name", name)
+	}
+
+	if len(extensions) < 1 {
+		return fmt.Errorf("you need to set at least one extension for the kind %s", name)
+	}
+
+	for _, extension := range extensions {
+		if len(strings.Trim(extension, ".")) < 1 {
+			return fmt.Errorf("'%s' isn't a valid extension", extension)
+		}
+	}
+
+	config.BWSConfig.SetKind(name, extensions)
+
+	return nil
+}
+
+// RemoveKind removes the kind with the provided name from the config.
+func RemoveKind(name string) error {
+	if _, ok := config.BWSConfig.Kind(name); !ok {
+		return fmt.Errorf("there is no kind called %s", name)
+	}
+
+	delete(config.BWSConfig.Kinds, strings.ToLower(name))
+
+	return nil
+}
@@ -7,7 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/skillptm/bws/internal/cache"
 	"github.com/skillptm/bws/internal/config"
@@ -35,28 +38,38 @@ func SetCPUThreads(threads int) error {
 	return nil
 }
 
-// setConfigDirs checks if all provided folders exist and then sets them to the correct attribute of BWSConfig
-func setConfigDirs(configType string, newDirs []string) error {
+// checkDirs formats the provided folders and checks if all of them exist
+func checkDirs(configType string, newDirs []string) ([]string, error) {
 	// properly format the provided paths
 	for index, element := range newDirs {
 		newDirs[index] = util.FormatEntry(element, true)
 	}
 	newDirs, err := util.InsertUsername(newDirs)
 	if err != nil {
-		return fmt.Errorf("couldn't replace '<USERNAME>'; %s", err.Error())
+		return newDirs, fmt.Errorf("couldn't replace '<USERNAME>'; %s", err.Error())
 	}
 
 	//check if all dirs prvoided exist and aren't a file
 	for _, dir := range newDirs {
 		if fileInfo, err := os.Stat(dir); err != nil {
-			return fmt.Errorf("%s couldn't be added to %s, because it either can't be accessed or doesn't exist", dir, configType)
+			return newDirs, fmt.Errorf("%s couldn't be added to %s, because it either can't be accessed or doesn't exist", dir, configType)
 		} else {
 			if !fileInfo.IsDir() {
-				return fmt.Errorf("%s couldn't be added to %s, because it's a file and not a folder", dir, configType)
+				return newDirs, fmt.Errorf("%s couldn't be added to %s, because it's a file and not a folder", dir, configType)
 			}
 		}
 	}
 
+	return newDirs, nil
+}
+
+// setConfigDirs checks if all provided folders exist and then sets them to the correct attribute of BWSConfig
+func setConfigDirs(configType string, newDirs []string) error {
+	newDirs, err := checkDirs(configType, newDirs)
+	if err != nil {
+		return err
+	}
+
 	switch configType {
 	case "MainDirs":
 		config.BWSConfig.MainDirs = newDirs
@@ -68,6 +81,9 @@ func setConfigDirs(configType string, newDirs []string) error {
 		config.BWSConfig.ExcludeDirs = newDirs
 	}
 
+	// the preset scopes are built from the MainDirs, ExcludeSubMainDirs and SecondaryDirs
+	config.BWSConfig.SetPresetScopes()
+
 	return nil
 }
 
@@ -88,7 +104,7 @@ func SetMainDirs(newDirs []string) error {
 		return err
 	}
 
-	cache.EntrieFilesystem.SetupProperly = false
+	cache.EntrieFilesystem().SetupProperly.Store(false)
 
 	return nil
 }
@@ -107,7 +123,7 @@ func SetExcludeSubMainDirs(newDirs []string) error {
 		return err
 	}
 
-	cache.EntrieFilesystem.SetupProperly = false
+	cache.EntrieFilesystem().SetupProperly.Store(false)
 
 	return nil
 }
@@ -126,7 +142,7 @@ func SetSecondaryDirs(newDirs []string) error {
 		return err
 	}
 
-	cache.EntrieFilesystem.SetupProperly = false
+	cache.EntrieFilesystem().SetupProperly.Store(false)
 
 	return nil
 }
@@ -145,7 +161,7 @@ func SetExcludeDirs(newDirs []string) error {
 		return err
 	}
 
-	cache.EntrieFilesystem.SetupProperly = false
+	cache.EntrieFilesystem().SetupProperly.Store(false)
 
 	return nil
 }
@@ -161,5 +177,287 @@ By default this valus is ".git", "bin", "node_modules" and "steamapps".
 func SetExcludeDirsByName(newDirs []string) {
 	config.BWSConfig.ExcludeDirsByName = newDirs
 
-	cache.EntrieFilesystem.SetupProperly = false
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+}
+
+/*
+SetExcludePatterns allows you to set the ExcludePatterns for the config that controls the cache generation.
+The patterns use the syntax of .gitignore files (e.g. "target/", "docs/**", "*.tmp", "!keep.me" or "build-*") and apply to files as well as folders.
+Patterns that contain a "/" are matched against the path relative to the root of the scope the entry was found in,
+all others are matched against the name of the entry. As in a .gitignore file the last matching pattern decides.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default there are no ExcludePatterns.
+*/
+func SetExcludePatterns(patterns []string) error {
+	err := config.BWSConfig.SetExcludePatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetUseIgnoreFiles allows you to turn on, that the ignore files inside of every folder are honoured during the cache generation.
+Like ripgrep, the rules of a .gitignore, .ignore and .bwsignore file apply to the folder they're in and all of its subfolders,
+where the rules of deeper folders take precedence and inside of the same folder .bwsignore beats .ignore, which beats .gitignore.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is false.
+*/
+func SetUseIgnoreFiles(useIgnoreFiles bool) {
+	config.BWSConfig.UseIgnoreFiles = useIgnoreFiles
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+}
+
+/*
+SetIgnoreFiles allows you to set the names of the ignore files, that are honoured after turning them on with SetUseIgnoreFiles.
+Inside of the same folder the rules of a later name take precedence over the ones of an earlier name.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is ".gitignore", ".ignore" and ".bwsignore".
+*/
+func SetIgnoreFiles(fileNames []string) error {
+	for _, fileName := range fileNames {
+		if len(fileName) < 1 || fileName == "." || fileName == ".." || strings.ContainsAny(fileName, "/\\") {
+			return fmt.Errorf("'%s' isn't a valid name of an ignore file", fileName)
+		}
+	}
+
+	config.BWSConfig.IgnoreFiles = fileNames
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetSymlinks allows you to set how links to folders are handled during the cache generation:
+  - "never": links are cached, but the folders they point to aren't crawled
+  - "roots": links are only followed, if they point inside the roots of the scope they were found in
+  - "always": links are always followed
+
+As soon as links get followed, every folder is only crawled once (identified by its device and inode), so loops can't occur.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is "never".
+*/
+func SetSymlinks(policy string) error {
+	if policy != config.SymlinksNever && policy != config.SymlinksWithinRoots && policy != config.SymlinksAlways {
+		return fmt.Errorf("%s isn't a valid symlink policy, use \"never\", \"roots\" or \"always\"", policy)
+	}
+
+	config.BWSConfig.Symlinks = policy
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetOneFilesystem allows you to set, that the cache generation doesn't cross into other filesystems than the one a root is on.
+Mount points of other filesystems are still cached, but not crawled.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is false.
+*/
+func SetOneFilesystem(oneFilesystem bool) {
+	config.BWSConfig.OneFilesystem = oneFilesystem
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+}
+
+/*
+SetExcludeFSTypes allows you to set the filesystem types, whose mount points will not be included in the cache generation at all.
+The types are read from /proc/self/mountinfo and may contain wildcards (e.g. "fuse.*"), so this only has an effect on linux.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is "proc", "sysfs", "tmpfs", "devtmpfs", "devpts", "cgroup", "cgroup2", "debugfs", "tracefs", "securityfs" and "fuse.*".
+*/
+func SetExcludeFSTypes(fsTypes []string) error {
+	for _, fsType := range fsTypes {
+		if _, err := path.Match(fsType, ""); err != nil {
+			return fmt.Errorf("%s isn't a valid filesystem type pattern; %s", fsType, err.Error())
+		}
+	}
+
+	config.BWSConfig.ExcludeFSTypes = fsTypes
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetReadTimeout allows you to set how long reading a single folder may take during the cache generation, before it's skipped.
+This keeps a dead network mount from stalling the whole generation. A timeout of 0 means there is no limit.
+
+By default this value is 10 seconds.
+*/
+func SetReadTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return errors.New("the read timeout can't be negative")
+	}
+
+	config.BWSConfig.ReadTimeout = timeout
+
+	return nil
+}
+
+/*
+SetCompoundExtensions allows you to set the extensions made up of multiple parts (e.g. ".tar.gz"), that should be kept together instead of only using the part after the last ".".
+They're matched case insensitive and the leading "." is optional. Searching for the last part (e.g. ".gz") still finds these files.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is ".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".d.ts", ".min.js" and ".min.css".
+*/
+func SetCompoundExtensions(extensions []string) error {
+	for _, extension := range extensions {
+		if len(strings.Trim(extension, ".")) < 1 {
+			return fmt.Errorf("'%s' isn't a valid extension", extension)
+		}
+	}
+
+	config.BWSConfig.SetCompoundExtensions(extensions)
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetSniffMIME allows you to turn on the detection of MIME types from the first bytes of each file during the cache generation.
+This finds scripts without an extension, misnamed downloads and the like with "mime:" in the searchString (e.g. "mime:text/x-python").
+Without it the MIME types only get guessed from the extensions.
+
+Only new or changed files get read during an update of the cache, at most as many per second as set with SetSniffRate.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is false.
+*/
+func SetSniffMIME(sniff bool) {
+	config.BWSConfig.SniffMIME = sniff
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+}
+
+/*
+SetSniffRate allows you to set how many files per second may be read to detect their MIME type or index their content, so it doesn't slow down other I/O.
+A rate of 0 means there is no limit.
+
+By default this value is 1000.
+*/
+func SetSniffRate(rate int) error {
+	if rate < 0 {
+		return errors.New("the sniff rate can't be negative")
+	}
+
+	config.BWSConfig.SniffRate = rate
+
+	return nil
+}
+
+/*
+SetIndexContent allows you to turn on the content index for text files during the cache generation.
+Afterwards you can search for files by the words inside of them with "content:" in the searchString (e.g. "config content:listen_port").
+A content term may also be a part of a word, every result shows the first line containing it as its Snippet.
+
+Only files that are valid UTF-8 and not larger than the ContentMaxSize get indexed. During an update of the cache only new or changed files get read,
+at most as many per second as set with SetSniffRate.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is false.
+*/
+func SetIndexContent(index bool) {
+	config.BWSConfig.IndexContent = index
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+}
+
+/*
+SetContentMaxSize allows you to set the size in bytes up to which text files get their content indexed.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is 1 MiB.
+*/
+func SetContentMaxSize(size int64) error {
+	if size < 1 {
+		return errors.New("the content max size has to be at least 1 byte")
+	}
+
+	config.BWSConfig.ContentMaxSize = size
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetIndexArchives allows you to turn on the listing of members inside of .zip, .tar, .tar.gz and .tgz files during the cache generation.
+Members get searched like any other file and their path contains the path of the archive (e.g. "C:/backup.zip!/docs/plan.pdf").
+Results that are archive members have the path of their archive set.
+
+During an update of the cache only new or changed archives get read, at most as many per second as set with SetSniffRate.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is false.
+*/
+func SetIndexArchives(index bool) {
+	config.BWSConfig.IndexArchives = index
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+}
+
+/*
+SetArchiveLimits allows you to set the size in bytes up to which archives get their members listed and how many members get listed per archive.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default the limits are 256 MiB and 10000 members.
+*/
+func SetArchiveLimits(maxSize int64, maxMembers int) error {
+	if maxSize < 1 || maxMembers < 1 {
+		return errors.New("the archive limits have to be at least 1")
+	}
+
+	config.BWSConfig.ArchiveMaxSize = maxSize
+	config.BWSConfig.ArchiveMaxMembers = maxMembers
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+SetExtractMetadata allows you to turn on reading the metadata of media files and documents during the cache generation.
+That is the date taken, camera and dimensions from the EXIF data of JPEGs and TIFFs, the artist, album, title and year from the tags of
+MP3s, FLACs and MP4s and the title, author and subject of PDFs, Office documents (.docx, .xlsx, .pptx) and EPUBs.
+Afterwards you can search for them with terms inside of the searchString (e.g. "artist:queen", `album:"a night at the opera"` or "taken:2023-06").
+The title, author and subject also get matched by the normal search, but those results get ranked lower than matching file names.
+
+During an update of the cache only new or changed files get read, at most as many per second as set with SetSniffRate.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+By default this value is false.
+*/
+func SetExtractMetadata(extract bool) {
+	config.BWSConfig.ExtractMetadata = extract
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
 }
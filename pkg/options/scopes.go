@@ -0,0 +1,141 @@
+// Package options allows you to set values from the configaration of the cache generation and search.
+package options
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// checkCustomScope makes sure the name can be used for a scope, that isn't one of the presets
+func checkCustomScope(name string) error {
+	if len(name) < 1 {
+		return errors.New("a scope needs a name")
+	}
+
+	if name == config.MainScope || name == config.SecondaryScope {
+		return fmt.Errorf("the scope %s is a preset, use the MainDirs, ExcludeSubMainDirs and SecondaryDirs set functions to change it", name)
+	}
+
+	return nil
+}
+
+/*
+SetScope adds a scope to the config or replaces the roots, excludes and extended flag of an existing one.
+A scope is a named part of the cache, that gets crawled, updated and searched on its own. You can search specific scopes with bws.SearchScopes.
+
+Folders that are the root of a scope always belong to that scope, even if they're inside the roots of another scope.
+Extended scopes only get searched in an extended search, or if you ask for them explicitly.
+New scopes get updated every 3 minutes, or every 30 minutes if they're extended. Use SetScopeRefresh to change that.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+The scopes "main" and "secondary" are the presets that get built from the MainDirs and SecondaryDirs, so they can't be set here.
+*/
+func SetScope(name string, roots []string, excludes []string, extended bool) error {
+	if err := checkCustomScope(name); err != nil {
+		return err
+	}
+
+	if len(roots) < 1 {
+		return fmt.Errorf("you need to set at least one root folder for the scope %s", name)
+	}
+
+	roots, err := checkDirs(name, roots)
+	if err != nil {
+		return err
+	}
+
+	excludes, err = checkDirs(name, excludes)
+	if err != nil {
+		return err
+	}
+
+	scope := config.BWSConfig.Scope(name)
+	if scope == nil {
+		scope = &config.Scope{Name: name, Refresh: config.MainRefresh}
+		if extended {
+			scope.Refresh = config.SecondaryRefresh
+		}
+
+		config.BWSConfig.Scopes = append(config.BWSConfig.Scopes, scope)
+	}
+
+	scope.Roots = roots
+	scope.Excludes = excludes
+	scope.Extended = extended
+
+	cache.EntrieFilesystem().SetupProperly.Store(false)
+
+	return nil
+}
+
+/*
+RemoveScope removes a scope from the config.
+
+Using this function will cause the cache to regenerate before the next bws.Search execution.
+
+The scopes "main" and "secondary" are presets and can't be removed.
+*/
+func RemoveScope(name string) error {
+	if err := checkCustomScope(name); err != nil {
+		return err
+	}
+
+	for index, scope := range config.BWSConfig.Scopes {
+		if scope.Name == name {
+			// the old slice might still get iterated by an update or a generation of the cache, so it stays as it is
+			config.BWSConfig.Scopes = slices.Delete(slices.Clone(config.BWSConfig.Scopes), index, index+1)
+			cache.EntrieFilesystem().SetupProperly.Store(false)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("there is no scope called %s", name)
+}
+
+/*
+SetScopeRefresh allows you to set how often a scope gets updated in the background. A refresh of 0 means it never gets updated.
+
+By default this value is 3 minutes for the "main" scope and 30 minutes for the "secondary" scope.
+*/
+func SetScopeRefresh(name string, refresh time.Duration) error {
+	if refresh < 0 {
+		return errors.New("the refresh of a scope can't be negative")
+	}
+
+	scope := config.BWSConfig.Scope(name)
+	if scope == nil {
+		return fmt.Errorf("there is no scope called %s", name)
+	}
+
+	scope.Refresh = refresh
+
+	return nil
+}
+
+/*
+SetScopeBoost allows you to set the points that get added to the rank of every result from a scope.
+A negative boost ranks the results of the scope lower.
+
+By default this value is 0 for every scope.
+*/
+func SetScopeBoost(name string, boost int) error {
+	scope := config.BWSConfig.Scope(name)
+	if scope == nil {
+		return fmt.Errorf("there is no scope called %s", name)
+	}
+
+	scope.Boost = boost
+
+	return nil
+}
@@ -0,0 +1,322 @@
+// Package rpc exposes the search of bws as JSON-RPC 2.0, e.g. over the stdin and stdout of "bws rpc" for editor integrations.
+package rpc
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/skillptm/bws"
+	"github.com/skillptm/bws/pkg/client"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	MethodSearch  string = "search"          // runs a search, see searchParams
+	MethodCancel  string = "$/cancelRequest" // a notification, that cancels the request with the id of its params
+	MethodStatus  string = "status"          // returns the IndexStatus
+	MethodStats   string = "stats"           // returns the ScopeStats of all scopes
+	MethodReindex string = "reindex"         // updates the scopes of its params (or all of them) and returns their ScopeStats
+
+	NotifyResult     string = "search/result"     // a single Result of a streamed search, in the ranked order
+	NotifyIncomplete string = "search/incomplete" // the streamed results came from an incomplete cache, the complete ones follow
+	NotifyReset      string = "search/reset"      // the cache is complete, the results sent so far get replaced by the following ones
+
+	CodeParseError     int = -32700
+	CodeInvalidRequest int = -32600
+	CodeMethodNotFound int = -32601
+	CodeInvalidParams  int = -32602
+	CodeInternalError  int = -32603
+	CodeCancelled      int = -32800 // the same code the language server protocol uses for cancelled requests
+
+	maxLineSize int = 16 << 20 // the largest message we read
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error object of a failed request
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+/*
+searchParams are the params of a search. If Scopes are provided they get searched, otherwise Extended decides.
+
+With Stream set, every Result is sent as a NotifyResult notification before the response, which then only holds the count.
+*/
+type searchParams struct {
+	Query    string   `json:"query"`
+	Ext      []string `json:"ext"`
+	Extended bool     `json:"extended"`
+	Scopes   []string `json:"scopes"`
+	Limit    int      `json:"limit"`
+	Stream   bool     `json:"stream"`
+}
+
+// streamedResult is the params of a NotifyResult notification, ID is the id of the search it belongs to
+type streamedResult struct {
+	ID     json.RawMessage `json:"id"`
+	Result bws.Result      `json:"result"`
+}
+
+// idParams are the params of a MethodCancel, NotifyIncomplete or NotifyReset notification, that only hold the id of the search
+type idParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// reindexParams are the params of a reindex
+type reindexParams struct {
+	Scopes []string `json:"scopes"`
+}
+
+// server answers the messages of a single connection
+type server struct {
+	writeMutex sync.Mutex
+	writer     *bufio.Writer
+
+	searchMutex sync.Mutex
+	searches    map[string]chan struct{} // the channels of the running searches by their id, that get closed to cancel them
+
+	wg sync.WaitGroup
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Serve reads JSON-RPC 2.0 messages from the reader, one per line, and writes the responses and notifications to the writer, one per line.
+
+Every request runs on its own, so a search can be cancelled with a MethodCancel notification while it runs and answers with CodeCancelled.
+Everything runs on the bws daemon, if it's running, otherwise on the cache of this process.
+Serve returns once the reader ends and all running requests have been answered.
+*/
+func Serve(reader io.Reader, writer io.Writer) error {
+	newServer := server{writer: bufio.NewWriter(writer), searches: make(map[string]chan struct{})}
+	defer newServer.wg.Wait()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) < 1 {
+			continue
+		}
+
+		request := message{}
+		if err := json.Unmarshal(line, &request); err != nil {
+			newServer.respondError(json.RawMessage("null"), CodeParseError, fmt.Sprintf("couldn't parse the message; %s", err.Error()))
+			continue
+		}
+
+		if request.JSONRPC != "2.0" || len(request.Method) < 1 {
+			newServer.respondError(request.ID, CodeInvalidRequest, "the message isn't a JSON-RPC 2.0 request")
+			continue
+		}
+
+		// cancelling has to happen right away, so it doesn't wait behind the search it cancels
+		if request.Method == MethodCancel {
+			newServer.cancel(request.Params)
+			continue
+		}
+
+		cancelled := make(chan struct{})
+		if request.Method == MethodSearch && len(request.ID) > 0 {
+			newServer.startSearch(request.ID, cancelled)
+		}
+
+		newServer.wg.Add(1)
+		go func() {
+			defer newServer.wg.Done()
+			newServer.handle(request, cancelled)
+		}()
+	}
+
+	return scanner.Err()
+}
+
+// handle answers a single request, a search stops once cancelled gets closed. Notifications (requests without an id) get no response
+func (server *server) handle(request message, cancelled <-chan struct{}) {
+	var result any
+	var err *rpcError
+
+	switch request.Method {
+	case MethodSearch:
+		result, err = server.search(request.ID, request.Params, cancelled)
+	case MethodStatus:
+		result = client.GetIndexStatus()
+	case MethodStats:
+		result = client.Stats()
+	case MethodReindex:
+		result, err = reindex(request.Params)
+	default:
+		err = &rpcError{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method '%s'", request.Method)}
+	}
+
+	if len(request.ID) < 1 {
+		return
+	}
+
+	if err != nil {
+		server.respondError(request.ID, err.Code, err.Message)
+		return
+	}
+
+	server.write(message{JSONRPC: "2.0", ID: request.ID, Result: result})
+}
+
+// startSearch registers the cancelled channel of the search with the id, before it starts running, so it can always be cancelled
+func (server *server) startSearch(id json.RawMessage, cancelled chan struct{}) {
+	server.searchMutex.Lock()
+	defer server.searchMutex.Unlock()
+
+	server.searches[string(id)] = cancelled
+}
+
+// endSearch removes the search with the id, so it can't be cancelled anymore
+func (server *server) endSearch(id json.RawMessage) {
+	server.searchMutex.Lock()
+	defer server.searchMutex.Unlock()
+
+	delete(server.searches, string(id))
+}
+
+// cancel stops the search with the id inside of the params, unknown ids are ignored as the search might have already ended
+func (server *server) cancel(rawParams json.RawMessage) {
+	params := idParams{}
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		return
+	}
+
+	server.searchMutex.Lock()
+	defer server.searchMutex.Unlock()
+
+	if cancelled, ok := server.searches[string(params.ID)]; ok {
+		close(cancelled)
+		delete(server.searches, string(params.ID))
+	}
+}
+
+// search runs the search of the params, that stops once cancelled gets closed, and returns its Response, or its count if it was streamed
+func (server *server) search(id json.RawMessage, rawParams json.RawMessage, cancelled <-chan struct{}) (any, *rpcError) {
+	if len(id) > 0 {
+		defer server.endSearch(id)
+	}
+
+	params := searchParams{}
+	if len(rawParams) > 0 {
+		if err := json.Unmarshal(rawParams, &params); err != nil {
+			return nil, &rpcError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	if params.Limit < 0 {
+		return nil, &rpcError{Code: CodeInvalidParams, Message: "the limit can't be negative"}
+	}
+
+	// stopped is what every search that got cancelled answers with
+	stopped := &rpcError{Code: CodeCancelled, Message: "the search was cancelled"}
+
+	query := client.Query{SearchString: params.Query, FileExtensions: params.Ext, Extended: params.Extended, Scopes: params.Scopes, Limit: params.Limit}
+
+	response, brokenEarly := client.SearchUntil(query, cancelled)
+	if brokenEarly {
+		return nil, stopped
+	}
+
+	if !params.Stream {
+		return response, nil
+	}
+
+	server.stream(id, response.Results)
+
+	if response.Incomplete {
+		server.notify(NotifyIncomplete, idParams{ID: id})
+
+		select {
+		case <-response.Ready:
+		case <-cancelled:
+			return nil, stopped
+		}
+
+		if response, brokenEarly = client.SearchUntil(query, cancelled); brokenEarly {
+			return nil, stopped
+		}
+
+		server.notify(NotifyReset, idParams{ID: id})
+		server.stream(id, response.Results)
+	}
+
+	return map[string]int{"count": len(response.Results)}, nil
+}
+
+// stream sends the results of the search with the id as NotifyResult notifications
+func (server *server) stream(id json.RawMessage, results []bws.Result) {
+	for _, result := range results {
+		server.notify(NotifyResult, streamedResult{ID: id, Result: result})
+	}
+}
+
+// reindex updates the scopes of the params (or all of them) and returns their ScopeStats
+func reindex(rawParams json.RawMessage) (any, *rpcError) {
+	params := reindexParams{}
+	if len(rawParams) > 0 {
+		if err := json.Unmarshal(rawParams, &params); err != nil {
+			return nil, &rpcError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	if err := client.UpdateScopes(params.Scopes); err != nil {
+		return nil, &rpcError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	return client.Stats(), nil
+}
+
+// notify sends a notification with the method and params
+func (server *server) notify(method string, params any) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+
+	server.write(message{JSONRPC: "2.0", Method: method, Params: encoded})
+}
+
+// respondError sends an error response for the request with the id
+func (server *server) respondError(id json.RawMessage, code int, text string) {
+	if len(id) < 1 {
+		id = json.RawMessage("null")
+	}
+
+	server.write(message{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: text}})
+}
+
+// write sends the message as a single line, messages from different requests never get mixed up
+func (server *server) write(msg message) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		encoded, _ = json.Marshal(message{JSONRPC: "2.0", ID: msg.ID, Error: &rpcError{Code: CodeInternalError, Message: err.Error()}})
+	}
+
+	server.writeMutex.Lock()
+	defer server.writeMutex.Unlock()
+
+	server.writer.Write(append(encoded, '\n'))
+	server.writer.Flush()
+}
@@ -0,0 +1,344 @@
+// Package server exposes the search of bws as an HTTP/JSON API, that can be mounted into any http server.
+package server
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/skillptm/bws/pkg/client"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// errorBody is the JSON body of every failed request
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// searchParams are the query parameters of a search
+type searchParams struct {
+	query      string
+	extensions []string
+	extended   bool
+	scopes     []string
+	limit      int
+	wait       bool
+}
+
+/*
+hostGuard only passes on requests, whose Host (and Origin, if they have one) is localhost, a loopback address or one of the allowed hosts.
+
+This keeps other websites open in the browser of the user from using the API, be it directly or through DNS rebinding.
+*/
+type hostGuard struct {
+	allowed map[string]bool
+	next    http.Handler
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// reindexMutex is held while a reindex runs, so the requests can't start overlapping crawls
+var reindexMutex sync.Mutex
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+NewHandler returns an http.Handler with these routes, which all answer with JSON:
+  - GET /search?q=&ext=&extended=&scope=&limit=&wait=: the Response of the search, ext and scope can be repeated or comma separated
+  - GET /status: the IndexStatus
+  - POST /reindex?scope=&wait=: updates the scopes (or all of them) in the background, or before answering if wait is set
+  - GET /stats: the ScopeStats of every scope
+
+A search asking for "text/event-stream" (or with stream=true) gets its results as server-sent events instead, see streamSearch.
+Only one reindex runs at a time, another one gets rejected with 409 until it's done.
+Everything runs on the bws daemon, if it's running, otherwise on the cache of this process.
+To mount it below a prefix, wrap it with http.StripPrefix (e.g. mux.Handle("/bws/", http.StripPrefix("/bws", server.NewHandler()))).
+
+Requests for another Host than localhost or a loopback address get rejected with 403, as do requests from the pages of another Origin.
+If the handler is reached by other names (e.g. the address of the machine), they have to be provided as the allowedHosts, ports are ignored.
+*/
+func NewHandler(allowedHosts ...string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /search", handleSearch)
+	mux.HandleFunc("GET /status", handleStatus)
+	mux.HandleFunc("POST /reindex", handleReindex)
+	mux.HandleFunc("GET /stats", handleStats)
+
+	guard := hostGuard{allowed: make(map[string]bool), next: mux}
+	for _, host := range allowedHosts {
+		guard.allowed[hostname(host)] = true
+	}
+
+	return &guard
+}
+
+// ServeHTTP rejects the request with 403, if its Host or Origin isn't allowed, otherwise it gets passed on
+func (guard *hostGuard) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if !guard.allows(request.Host) {
+		writeError(writer, http.StatusForbidden, fmt.Errorf("the host '%s' isn't allowed", request.Host))
+		return
+	}
+
+	if origin := request.Header.Get("Origin"); len(origin) > 0 {
+		if parsed, err := url.Parse(origin); err != nil || !guard.allows(parsed.Host) {
+			writeError(writer, http.StatusForbidden, fmt.Errorf("the origin '%s' isn't allowed", origin))
+			return
+		}
+	}
+
+	guard.next.ServeHTTP(writer, request)
+}
+
+// allows checks if the host is localhost, a loopback address or one of the allowed hosts
+func (guard *hostGuard) allows(host string) bool {
+	host = hostname(host)
+	if host == "localhost" || guard.allowed[host] {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostname returns the host without its port and the brackets of an IPv6 address, in lower case
+func hostname(host string) string {
+	if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+		host = withoutPort
+	}
+
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+// handleSearch answers a search with its Response, or streams it if the client asked for that
+func handleSearch(writer http.ResponseWriter, request *http.Request) {
+	params, err := parseSearchParams(request)
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.URL.Query().Get("stream") == "true" || strings.Contains(request.Header.Get("Accept"), "text/event-stream") {
+		streamSearch(writer, request, params)
+		return
+	}
+
+	// the search stops, once the client disconnects
+	query := client.Query{SearchString: params.query, FileExtensions: params.extensions, Extended: params.extended, Scopes: params.scopes, Limit: params.limit}
+
+	response, brokenEarly := client.SearchUntil(query, request.Context().Done())
+	if brokenEarly {
+		return
+	}
+
+	if params.wait && response.Incomplete {
+		select {
+		case <-response.Ready:
+		case <-request.Context().Done():
+			return
+		}
+
+		if response, brokenEarly = client.SearchUntil(query, request.Context().Done()); brokenEarly {
+			return
+		}
+	}
+
+	writeJSON(writer, http.StatusOK, response)
+}
+
+/*
+streamSearch sends the results of the search as server-sent events, one "result" event per Result in their ranked order.
+
+If the cache was still being generated, an "incomplete" event follows. Once the cache is complete the search runs again,
+which starts with a "reset" event, after which the complete results get sent. The stream always ends with a "done" event holding the count.
+A client that disconnects stops the search.
+*/
+func streamSearch(writer http.ResponseWriter, request *http.Request, params *searchParams) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writeError(writer, http.StatusInternalServerError, fmt.Errorf("the response writer doesn't support streaming"))
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+
+	// send writes a single event and flushes it right away
+	send := func(event string, data any) {
+		encoded, _ := json.Marshal(data)
+		fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event, encoded)
+		flusher.Flush()
+	}
+
+	// the search stops, once the client disconnects
+	query := client.Query{SearchString: params.query, FileExtensions: params.extensions, Extended: params.extended, Scopes: params.scopes, Limit: params.limit}
+
+	response, brokenEarly := client.SearchUntil(query, request.Context().Done())
+	if brokenEarly {
+		return
+	}
+
+	for _, result := range response.Results {
+		send("result", result)
+	}
+
+	if response.Incomplete {
+		send("incomplete", struct{}{})
+
+		select {
+		case <-response.Ready:
+		case <-request.Context().Done():
+			return
+		}
+
+		if response, brokenEarly = client.SearchUntil(query, request.Context().Done()); brokenEarly {
+			return
+		}
+
+		send("reset", struct{}{})
+		for _, result := range response.Results {
+			send("result", result)
+		}
+	}
+
+	send("done", map[string]int{"count": len(response.Results)})
+}
+
+// parseSearchParams reads the searchParams from the query of the request
+func parseSearchParams(request *http.Request) (*searchParams, error) {
+	query := request.URL.Query()
+	params := searchParams{
+		query:      query.Get("q"),
+		extensions: splitValues(query["ext"]),
+		scopes:     splitValues(query["scope"]),
+	}
+
+	var err error
+	if params.extended, err = parseBool(query.Get("extended")); err != nil {
+		return nil, fmt.Errorf("extended has to be true or false")
+	}
+
+	if params.wait, err = parseBool(query.Get("wait")); err != nil {
+		return nil, fmt.Errorf("wait has to be true or false")
+	}
+
+	if limit := query.Get("limit"); len(limit) > 0 {
+		if params.limit, err = strconv.Atoi(limit); err != nil || params.limit < 0 {
+			return nil, fmt.Errorf("limit has to be a positive number")
+		}
+	}
+
+	return &params, nil
+}
+
+// handleStatus answers with the IndexStatus
+func handleStatus(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, client.GetIndexStatus())
+}
+
+// handleStats answers with the ScopeStats of all scopes
+func handleStats(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, client.Stats())
+}
+
+/*
+handleReindex updates the scopes from the query (or all of them).
+
+Without wait it answers right away with 202 Accepted and the update can be followed with /status,
+with wait it answers with the ScopeStats once the update is done. While another reindex runs, it answers with 409 Conflict.
+*/
+func handleReindex(writer http.ResponseWriter, request *http.Request) {
+	scopes := splitValues(request.URL.Query()["scope"])
+
+	wait, err := parseBool(request.URL.Query().Get("wait"))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("wait has to be true or false"))
+		return
+	}
+
+	// check the scopes up front, so an unknown one is an error even without waiting
+	known := make(map[string]bool)
+	for _, scopeStats := range client.Stats() {
+		known[scopeStats.Scope] = true
+	}
+
+	for _, scope := range scopes {
+		if !known[scope] {
+			writeError(writer, http.StatusNotFound, fmt.Errorf("there is no scope called '%s'", scope))
+			return
+		}
+	}
+
+	if !reindexMutex.TryLock() {
+		writeError(writer, http.StatusConflict, errors.New("a reindex is running already"))
+		return
+	}
+
+	if !wait {
+		go func() {
+			defer reindexMutex.Unlock()
+			client.UpdateScopes(scopes)
+		}()
+
+		writeJSON(writer, http.StatusAccepted, map[string][]string{"scopes": scopes})
+		return
+	}
+
+	err = client.UpdateScopes(scopes)
+	reindexMutex.Unlock()
+
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, client.Stats())
+}
+
+// splitValues splits every value at its commas and leaves out empty ones
+func splitValues(values []string) []string {
+	output := []string{}
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); len(element) > 0 {
+				output = append(output, element)
+			}
+		}
+	}
+
+	return output
+}
+
+// parseBool parses a boolean query parameter, a missing one is false
+func parseBool(value string) (bool, error) {
+	if len(value) < 1 {
+		return false, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// writeJSON answers with the status and the value as JSON
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+
+	json.NewEncoder(writer).Encode(value)
+}
+
+// writeError answers with the status and the err inside of an errorBody
+func writeError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, errorBody{Error: err.Error()})
+}
@@ -0,0 +1,72 @@
+// Package bws contains the main Search function and start up logic of bws.
+package bws
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/search"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// Result is a single ranked search result, the scope it was found in, if it's a (broken) link, its MIME type and content snippet
+type Result struct {
+	Path    string            `json:"path"`
+	Points  int               `json:"points"`
+	Scope   string            `json:"scope"`
+	IsLink  bool              `json:"isLink"`
+	Broken  bool              `json:"broken"`
+	MIME    string            `json:"mime"`              // sniffed if SniffMIME is turned on, otherwise guessed from the extension
+	Snippet string            `json:"snippet,omitempty"` // the line that matched the content terms of the search, if there were any
+	Archive string            `json:"archive,omitempty"` // the path of the archive the result is a member of, its Path then looks like "backup.zip!/docs/plan.pdf"
+	Fields  map[string]string `json:"fields,omitempty"`  // the metadata fields of media files (e.g. "artist" or "taken"), if ExtractMetadata is turned on
+}
+
+/*
+Response holds the ranked Results of a search.
+
+Incomplete is set, if the search ran while the dirs it searched were still being crawled for the first time.
+In that case Ready gets closed, once the crawl is done, so the search can be repeated for the complete results.
+*/
+type Response struct {
+	Results    []Result        `json:"results"`
+	Incomplete bool            `json:"incomplete"`
+	Ready      <-chan struct{} `json:"-"`
+}
+
+// newResponse returns a pointer to a Response struct with the rankedFiles as its Results
+func newResponse(rankedFiles *[]search.RankedFile, incomplete bool, fs *cache.Filesystem) *Response {
+	response := Response{
+		Results:    make([]Result, 0, len(*rankedFiles)),
+		Incomplete: incomplete,
+		Ready:      fs.Ready(),
+	}
+
+	for _, file := range *rankedFiles {
+		response.Results = append(response.Results, Result{
+			Path:    file.Path,
+			Points:  file.Points,
+			Scope:   file.Scope,
+			IsLink:  file.IsLink,
+			Broken:  file.Broken,
+			MIME:    file.MIME,
+			Snippet: file.Snippet,
+			Archive: file.Archive,
+			Fields:  file.Fields,
+		})
+	}
+
+	return &response
+}
+
+// Paths returns only the paths of the Results in their ranked order
+func (response *Response) Paths() []string {
+	paths := make([]string, 0, len(response.Results))
+
+	for _, result := range response.Results {
+		paths = append(paths, result.Path)
+	}
+
+	return paths
+}
@@ -0,0 +1,95 @@
+// Package bws contains the main Search function and start up logic of bws.
+package bws
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"time"
+
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+ScopeStats describes what the cache holds for a single scope.
+
+Size is the size of all files below the roots of the scope in bytes. Archive members are counted as files, but not in the Size.
+*/
+type ScopeStats struct {
+	Scope          string         `json:"scope"`
+	Extended       bool           `json:"extended"`
+	Ready          bool           `json:"ready"` // the scope has been fully crawled at least once
+	LastUpdate     time.Time      `json:"lastUpdate"`
+	Files          int            `json:"files"`
+	Folders        int            `json:"folders"`
+	ArchiveMembers int            `json:"archiveMembers"`
+	Size           int64          `json:"size"`
+	BrokenLinks    int            `json:"brokenLinks"`
+	Errors         int            `json:"errors"`
+	Excluded       int            `json:"excluded"`       // the entries left out during the last crawl, the ones inside of an excluded folder aren't counted
+	ExcludedCounts map[string]int `json:"excludedCounts"` // Excluded split up by the reason why the entries were left out
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Stats returns the ScopeStats of every scope of the config, in the order of the config.
+
+Scopes that haven't been crawled yet are part of it as well, but they aren't Ready and everything else is empty.
+*/
+func Stats() []ScopeStats {
+	fs := cache.EntrieFilesystem()
+	stats := []ScopeStats{}
+
+	fs.RLock()
+	defer fs.RUnlock()
+
+	for _, scope := range config.BWSConfig.Scopes {
+		scopeStats := ScopeStats{Scope: scope.Name, Extended: scope.Extended}
+
+		scopeCache, ok := fs.Scopes[scope.Name]
+		if !ok {
+			stats = append(stats, scopeStats)
+			continue
+		}
+
+		scopeStats.Ready = scopeCache.Ready
+		scopeStats.LastUpdate = scopeCache.LastUpdate
+		scopeStats.BrokenLinks = len(scopeCache.BrokenLinks)
+		scopeStats.Errors = len(scopeCache.Errors)
+		scopeStats.ExcludedCounts = make(map[string]int)
+		for reason, count := range scopeCache.ExcludedCounts {
+			scopeStats.Excluded += count
+			scopeStats.ExcludedCounts[reason] = count
+		}
+
+		for extension, lengthMaps := range scopeCache.Entries {
+			for _, entries := range lengthMaps {
+				if extension == "Folder" {
+					scopeStats.Folders += len(entries)
+					continue
+				}
+
+				for _, entry := range entries {
+					if len(entry.Archive) > 0 {
+						scopeStats.ArchiveMembers++
+					} else {
+						scopeStats.Files++
+					}
+				}
+			}
+		}
+
+		for _, root := range scope.Roots {
+			if usage, ok := scopeCache.Folders[root]; ok {
+				scopeStats.Size += usage.Size
+			}
+		}
+
+		stats = append(stats, scopeStats)
+	}
+
+	return stats
+}
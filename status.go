@@ -0,0 +1,111 @@
+// Package bws contains the main Search function and start up logic of bws.
+package bws
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skillptm/bws/internal/cache"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+const (
+	PhaseIdle     string = cache.PhaseIdle     // the cache was never generated
+	PhaseIndexing string = cache.PhaseIndexing // the cache is being generated from scratch, e.g. before the first Search
+	PhaseUpdating string = cache.PhaseUpdating // parts of the cache are being refreshed in the background
+	PhaseReady    string = cache.PhaseReady    // the cache is generated and no crawl is running
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+IndexStatus describes how far the generation of the cache has come.
+
+Scope is the name of the scope that is currently crawled and CurrentRoot the root of it, that the last read folder belongs to.
+ETA is only an estimate and stays 0, as long as there is nothing to base it on.
+*/
+type IndexStatus struct {
+	Phase          string        `json:"phase"`
+	Scope          string        `json:"scope"`
+	CurrentRoot    string        `json:"currentRoot"`
+	DirsVisited    int64         `json:"dirsVisited"`
+	EntriesIndexed int64         `json:"entriesIndexed"`
+	Elapsed        time.Duration `json:"elapsed"` // in nanoseconds inside of JSON
+	ETA            time.Duration `json:"eta"`     // in nanoseconds inside of JSON
+}
+
+// newIndexStatus converts a cache.ProgressSnapshot into an IndexStatus
+func newIndexStatus(snapshot cache.ProgressSnapshot) IndexStatus {
+	return IndexStatus{
+		Phase:          snapshot.Phase,
+		Scope:          snapshot.Scope,
+		CurrentRoot:    snapshot.CurrentRoot,
+		DirsVisited:    snapshot.DirsVisited,
+		EntriesIndexed: snapshot.EntriesIndexed,
+		Elapsed:        snapshot.Elapsed,
+		ETA:            snapshot.ETA,
+	}
+}
+
+// <---------------------------------------------------------------------------------------------------->
+
+// GetIndexStatus returns the current IndexStatus
+func GetIndexStatus() IndexStatus {
+	return newIndexStatus(cache.IndexProgress.Snapshot())
+}
+
+/*
+OnIndexProgress calls the callback with the current IndexStatus a few times per second while the cache gets generated or updated,
+and once more after the crawl has finished.
+
+The callback runs on bws' own goroutine, so it should return quickly. Call the returned function to remove the callback again.
+*/
+func OnIndexProgress(callback func(IndexStatus)) func() {
+	return cache.IndexProgress.Subscribe(func(snapshot cache.ProgressSnapshot) {
+		callback(newIndexStatus(snapshot))
+	})
+}
+
+/*
+SubscribeIndexProgress behaves like OnIndexProgress, but delivers the IndexStatus over a channel.
+
+The channel only ever holds the newest IndexStatus, so a slow reader skips outdated ones instead of blocking the crawl.
+Call the returned function to stop the subscription, this also closes the channel.
+*/
+func SubscribeIndexProgress() (<-chan IndexStatus, func()) {
+	var mutex sync.Mutex
+	closed := false
+	statusChan := make(chan IndexStatus, 1)
+
+	unsubscribe := OnIndexProgress(func(status IndexStatus) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if closed {
+			return
+		}
+
+		// drop the outdated status, if the reader hasn't taken it yet
+		select {
+		case <-statusChan:
+		default:
+		}
+
+		statusChan <- status
+	})
+
+	return statusChan, func() {
+		unsubscribe()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if !closed {
+			closed = true
+			close(statusChan)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package bws contains the main Search function and start up logic of bws.
+package bws
+
+// <---------------------------------------------------------------------------------------------------->
+
+import (
+	"fmt"
+
+	"github.com/skillptm/bws/internal/cache"
+	"github.com/skillptm/bws/internal/config"
+	"github.com/skillptm/bws/internal/util"
+)
+
+// <---------------------------------------------------------------------------------------------------->
+
+// FolderUsage is the disk usage of a folder, summed up over all files below it, including the ones inside of its sub folders
+type FolderUsage = cache.FolderUsage
+
+// <---------------------------------------------------------------------------------------------------->
+
+/*
+Usage returns the disk usage of the folder at path and of the folders up to depth levels below it, sorted by their size (largest first).
+With a depth of 0 only the folder itself is returned and a negative depth returns all folders below it.
+
+The usage is summed up while the scopes get crawled, so it's only as recent as the last update of the scope the folder belongs to.
+Links and the members of archives aren't counted. If the folder isn't inside of the cache, an error is returned.
+While the cache is being generated, it waits until it's done, like FindDuplicates.
+*/
+func Usage(path string, depth int) ([]FolderUsage, error) {
+	// the sizes are only complete once the scopes have been crawled, so we wait for the cache
+	fs := cache.EntrieFilesystem()
+	if !fs.SetupProperly.Load() {
+		fs = cache.Generate(config.BWSConfig.Scopes)
+	}
+
+	<-fs.Ready()
+
+	dirPath := util.FormatEntry(path, true)
+
+	usage := fs.Usage(dirPath, depth)
+	if len(usage) < 1 {
+		return usage, fmt.Errorf("'%s' isn't a folder inside of the cache", dirPath)
+	}
+
+	return usage, nil
+}